@@ -19,26 +19,42 @@ package beanstalk
 import (
 	"bufio"
 	"net"
+	"oionetdata/netdata"
+	"oionetdata/util"
 	"strings"
+	"time"
 )
 
 type collector struct {
-	addr  string
-	tubes []string
+	target      util.Target
+	tubes       []string
+	connectTime time.Duration
 }
 
 func NewCollector(addr string, tubes []string) *collector {
+	return NewTargetCollector(util.Target{Network: "tcp", Addr: addr, Name: addr}, tubes)
+}
+
+func NewTargetCollector(target util.Target, tubes []string) *collector {
 	return &collector{
-		addr:  addr,
-		tubes: tubes,
+		target: target,
+		tubes:  tubes,
 	}
 }
 
+func (c *collector) ConnectTime() time.Duration {
+	return c.connectTime
+}
+
 func SendCommand(conn net.Conn, cmd string, prefix string, data map[string]string) error {
 	if _, err := conn.Write([]byte(cmd + "\r\n")); err != nil {
 		return err
 	}
 
+	// Lines that are neither headers nor "key: value", reported if no value
+	// could be read
+	var malformed []string
+	parsed := 0
 	scanner := bufio.NewScanner(conn)
 	for scanner.Scan() {
 		line := scanner.Text()
@@ -50,30 +66,41 @@ func SendCommand(conn net.Conn, cmd string, prefix string, data map[string]strin
 		}
 		kv := strings.Split(line, ": ")
 		if len(kv) != 2 {
+			if !strings.HasPrefix(line, "OK ") && line != "---" {
+				malformed = append(malformed, line)
+			}
 			continue
 		}
 		data[prefix+kv[0]] = kv[1]
+		parsed++
 	}
 
 	if err := scanner.Err(); err != nil {
 		return err
 	}
+	if parsed == 0 && len(malformed) > 0 {
+		return netdata.ParseErrorf("could not parse %q response: %q", cmd, malformed[0])
+	}
 
 	return nil
 }
 
 func (c *collector) Collect() (map[string]string, error) {
-	conn, err := net.Dial("tcp", c.addr)
+	conn, connectTime, err := c.target.Dial(util.DefaultDialTimeout)
 	if err != nil {
 		return nil, err
 	}
 	defer conn.Close()
+	c.connectTime = connectTime
 
 	data := map[string]string{}
 
 	if err = SendCommand(conn, "stats", "", data); err != nil {
 		return nil, err
 	}
+	if len(data) == 0 {
+		return nil, netdata.ProtocolErrorf("no stats in response from %s", c.target)
+	}
 	for _, tube := range c.tubes {
 		if err = SendCommand(conn, "stats-tube "+tube, "_"+tube+"_", data); err != nil {
 			return nil, err
@@ -25,6 +25,8 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+
+	"oionetdata/util"
 )
 
 type testServer struct {
@@ -198,3 +200,17 @@ func TestBeanstalkCollector(t *testing.T) {
 		t.Fatalf("expected error")
 	}
 }
+
+func TestSimulatedCollector(t *testing.T) {
+	var tubes = []string{"default", "oio", "oio-delete", "oio-rebuild"}
+	collector := NewSimulatedCollector(util.Target{Network: "tcp", Addr: ":11300", Name: "simulated"}, tubes)
+	data, err := collector.Collect()
+	if err != nil {
+		t.Fatalf("unexpected Collect error: %v", err)
+	}
+	for key := range data {
+		if _, ok := expected[key]; !ok {
+			t.Fatalf("unexpected simulated key %s", key)
+		}
+	}
+}
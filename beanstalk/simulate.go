@@ -0,0 +1,81 @@
+// OpenIO netdata collectors
+// Copyright (C) 2019 OpenIO SAS
+//
+// This library is free software; you can redistribute it and/or
+// modify it under the terms of the GNU Lesser General Public
+// License as published by the Free Software Foundation; either
+// version 3.0 of the License, or (at your option) any later version.
+//
+// This library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package beanstalk
+
+import (
+	"oionetdata/util"
+	"time"
+)
+
+type simulator struct {
+	sim   *util.Simulation
+	tubes []string
+}
+
+// NewSimulatedCollector returns a collector producing synthetic stats for
+// target and its tubes, without connecting to it
+func NewSimulatedCollector(target util.Target, tubes []string) *simulator {
+	return &simulator{
+		sim:   util.NewSimulation("beanstalk/" + target.String()),
+		tubes: tubes,
+	}
+}
+
+func (s *simulator) ConnectTime() time.Duration {
+	return time.Duration(s.sim.GaugeValue("connect_time", 100, 2000)) * time.Microsecond
+}
+
+var simulatedCommands = []string{
+	"put", "peek", "peek-ready", "peek-delayed", "peek-buried", "reserve", "use", "watch", "ignore", "delete",
+	"release", "bury", "kick", "stats", "stats-job", "stats-tube", "list-tubes", "list-tube-used",
+	"list-tubes-watched", "pause-tube",
+}
+
+func (s *simulator) Collect() (map[string]string, error) {
+	data := map[string]string{}
+	s.jobs("", data)
+	for _, cmd := range simulatedCommands {
+		data["cmd-"+cmd] = s.sim.Counter("cmd-"+cmd, 10)
+	}
+	data["job-timeouts"] = s.sim.Counter("job-timeouts", 0.1)
+	data["current-tubes"] = s.sim.Gauge("current-tubes", float64(len(s.tubes)+1), float64(len(s.tubes)+3))
+	data["current-connections"] = s.sim.Gauge("current-connections", 5, 30)
+	data["current-producers"] = s.sim.Gauge("current-producers", 1, 10)
+	data["current-workers"] = s.sim.Gauge("current-workers", 1, 20)
+	data["current-waiting"] = s.sim.Gauge("current-waiting", 0, 10)
+	data["total-connections"] = s.sim.Counter("total-connections", 1)
+	data["binlog-records-written"] = s.sim.Counter("binlog-records-written", 20)
+	data["binlog-records-migrated"] = s.sim.Counter("binlog-records-migrated", 2)
+
+	for _, tube := range s.tubes {
+		prefix := "_" + tube + "_"
+		s.jobs(prefix, data)
+		data[prefix+"current-using"] = s.sim.Gauge(prefix+"current-using", 0, 5)
+		data[prefix+"current-waiting"] = s.sim.Gauge(prefix+"current-waiting", 0, 5)
+		data[prefix+"current-watching"] = s.sim.Gauge(prefix+"current-watching", 1, 10)
+	}
+	return data, nil
+}
+
+// jobs sets the job counts shared by stats and stats-tube
+func (s *simulator) jobs(prefix string, data map[string]string) {
+	for _, state := range []string{"urgent", "ready", "reserved", "delayed", "buried"} {
+		key := prefix + "current-jobs-" + state
+		data[key] = s.sim.Gauge(key, 0, 200)
+	}
+	data[prefix+"total-jobs"] = s.sim.Counter(prefix+"total-jobs", 20)
+}
@@ -18,12 +18,14 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"log"
 	"oionetdata/beanstalk"
 	"oionetdata/collector"
+	"oionetdata/logger"
 	"oionetdata/netdata"
+	"oionetdata/util"
 	"os"
-	"strings"
 	"time"
 )
 
@@ -32,100 +34,114 @@ func main() {
 		log.Fatalf("argument required")
 	}
 	var targets string
+	var splay bool
+	var relabel string
 	fs := flag.NewFlagSet("", flag.ExitOnError)
-	fs.StringVar(&targets, "targets", "", "Comma separated list of Redis IP:PORT")
+	fs.StringVar(&targets, "targets", "", util.TargetUsage+"&tube=TUBE1&tube=TUBE2 (legacy: IP:PORT[:TUBE1][:TUBE2]...)")
+	fs.BoolVar(&splay, "splay", false, "Spread collections over the interval based on the host name")
+	fs.StringVar(&relabel, "relabel", "", "Path to the filter and relabel rules file")
+	fs.BoolVar(&util.Simulate, "simulate", false, "Produce synthetic values instead of querying services")
+	check := util.CheckFlag(fs)
+	describe := netdata.DescribeFlag(fs)
+	config := netdata.WorkerConfigFlag(fs)
+	logConf := logger.Flags(fs)
 	err := fs.Parse(os.Args[2:])
 	if err != nil {
 		log.Fatalln("ERROR: Beanstalk plugin: Could not parse args", err)
 	}
+	if err := logger.SetConfig(*logConf); err != nil {
+		log.Fatalln("ERROR: Beanstalk plugin: Invalid log configuration", err)
+	}
+	if *check {
+		os.Exit(checkConfig(targets, *config, relabel))
+	}
+	if relabel != "" {
+		if err := netdata.LoadRelabel(relabel); err != nil {
+			log.Fatalln("ERROR: Beanstalk plugin: Could not load relabel rules", err)
+		}
+	}
 	intervalSeconds := collector.ParseIntervalSeconds(os.Args[1])
+	interval := time.Duration(intervalSeconds) * time.Second
+	writer := netdata.NewDefaultWriter()
 
+	if *config != "" {
+		if targets != "" {
+			log.Fatalln("ERROR: Beanstalk plugin: --targets and --config are exclusive")
+		}
+		worker, err := netdata.LoadWorker(*config, interval, writer)
+		if err != nil {
+			log.Fatalln("ERROR: Beanstalk plugin: Could not load the worker configuration", err)
+		}
+		run(worker, *describe)
+		return
+	}
+
+	if targets == "" && util.Simulate {
+		targets = "tcp://127.0.0.1:11300?name=simulated&tube=default"
+	}
 	if targets == "" {
 		log.Fatalln("ERROR: Beanstalk plugin: missing targets")
 	}
+	parsed, err := util.ParseTargets(targets)
+	if err != nil {
+		log.Fatalln("ERROR: Beanstalk plugin: invalid targets", err)
+	}
 
-	writer := netdata.NewDefaultWriter()
-	worker := netdata.NewWorker(time.Duration(intervalSeconds)*time.Second, writer)
+	worker := netdata.NewWorker(interval, writer)
+	if splay {
+		worker.EnableSplay()
+	}
 
-	for _, target := range strings.Split(targets, ",") {
-		res := strings.Split(target, ":")
-		if len(res) < 2 {
-			log.Fatalln("Invalid parameter", target, "must be IP:PORT[:tube1][:tube2]...")
+	for _, target := range parsed {
+		if err := beanstalk.AddCharts(worker, target); err != nil {
+			log.Fatalln("ERROR: Beanstalk plugin:", err)
 		}
-		addr := res[0] + ":" + res[1]
-		tubes := res[2:]
-		collector := beanstalk.NewCollector(addr, tubes)
-		worker.AddCollector(collector)
-		instance := "beanstalk." + addr + ":global"
-
-		c := netdata.NewChart(instance, "jobs", "", "", "", "general", "beanstalk.job")
-		c.AddDimension("current-jobs-urgent", "urgent", netdata.AbsoluteAlgorithm)
-		c.AddDimension("current-jobs-ready", "ready", netdata.AbsoluteAlgorithm)
-		c.AddDimension("current-jobs-reserved", "reserved", netdata.AbsoluteAlgorithm)
-		c.AddDimension("current-jobs-delayed", "delayed", netdata.AbsoluteAlgorithm)
-		c.AddDimension("current-jobs-buried", "buried", netdata.AbsoluteAlgorithm)
-		c.AddDimension("total-jobs", "total", netdata.IncrementalAlgorithm)
-		c.AddDimension("jobs-timeouts", "timeouts", netdata.IncrementalAlgorithm)
-		worker.AddChart(c, collector)
-
-		c = netdata.NewChart(instance, "commands", "", "", "", "general", "beanstalk.commands")
-		c.AddDimension("cmd-put", "put", netdata.IncrementalAlgorithm)
-		c.AddDimension("cmd-peek", "peek", netdata.IncrementalAlgorithm)
-		c.AddDimension("cmd-peek-ready", "peek-ready", netdata.IncrementalAlgorithm)
-		c.AddDimension("cmd-peek-delayed", "peek-delayed", netdata.IncrementalAlgorithm)
-		c.AddDimension("cmd-peek-buried", "peek-buried", netdata.IncrementalAlgorithm)
-		c.AddDimension("cmd-reserve", "reserve", netdata.IncrementalAlgorithm)
-		c.AddDimension("cmd-use", "use", netdata.IncrementalAlgorithm)
-		c.AddDimension("cmd-watch", "watch", netdata.IncrementalAlgorithm)
-		c.AddDimension("cmd-ignore", "ignore", netdata.IncrementalAlgorithm)
-		c.AddDimension("cmd-delete", "delete", netdata.IncrementalAlgorithm)
-		c.AddDimension("cmd-release", "release", netdata.IncrementalAlgorithm)
-		c.AddDimension("cmd-bury", "bury", netdata.IncrementalAlgorithm)
-		c.AddDimension("cmd-kick", "kick", netdata.IncrementalAlgorithm)
-		c.AddDimension("cmd-stats", "stats", netdata.IncrementalAlgorithm)
-		c.AddDimension("cmd-stats-job", "stats-job", netdata.IncrementalAlgorithm)
-		c.AddDimension("cmd-stats-tube", "stats-tube", netdata.IncrementalAlgorithm)
-		c.AddDimension("cmd-list-tubes", "list-tubes", netdata.IncrementalAlgorithm)
-		c.AddDimension("cmd-list-tubes-used", "list-tubes-used", netdata.IncrementalAlgorithm)
-		c.AddDimension("cmd-list-tubes-watched", "list-tubes-watched", netdata.IncrementalAlgorithm)
-		c.AddDimension("cmd-pause-tube", "pause-tube", netdata.IncrementalAlgorithm)
-		worker.AddChart(c, collector)
-
-		c = netdata.NewChart(instance, "tubes", "", "", "", "general", "beanstalk.tubes")
-		c.AddDimension("current-tubes", "current", netdata.AbsoluteAlgorithm)
-		worker.AddChart(c, collector)
-
-		c = netdata.NewChart(instance, "connections", "", "", "", "general", "beanstalk.connections")
-		c.AddDimension("current-connections", "open", netdata.AbsoluteAlgorithm)
-		c.AddDimension("current-producers", "producers", netdata.AbsoluteAlgorithm)
-		c.AddDimension("current-workers", "workers", netdata.AbsoluteAlgorithm)
-		c.AddDimension("current-waiting", "waiting", netdata.AbsoluteAlgorithm)
-		c.AddDimension("total-connections", "total", netdata.IncrementalAlgorithm)
-		worker.AddChart(c, collector)
-
-		c = netdata.NewChart(instance, "binlog", "", "", "", "general", "beanstalk.binlog")
-		c.AddDimension("binlog-records-written", "written", netdata.IncrementalAlgorithm)
-		c.AddDimension("binlog-records-migrated", "compaction", netdata.IncrementalAlgorithm)
-		worker.AddChart(c, collector)
+	}
 
-		for _, tube := range tubes {
-			instance = "beanstalk." + addr + ":" + tube
-			c = netdata.NewChart(instance, "jobs", "", "", "", tube, "beanstalk.job")
-			c.AddDimension("_"+tube+"_current-jobs-urgent", "urgent", netdata.AbsoluteAlgorithm)
-			c.AddDimension("_"+tube+"_current-jobs-ready", "ready", netdata.AbsoluteAlgorithm)
-			c.AddDimension("_"+tube+"_current-jobs-reserved", "reserved", netdata.AbsoluteAlgorithm)
-			c.AddDimension("_"+tube+"_current-jobs-delayed", "delayed", netdata.AbsoluteAlgorithm)
-			c.AddDimension("_"+tube+"_current-jobs-buried", "buried", netdata.AbsoluteAlgorithm)
-			c.AddDimension("_"+tube+"_total-jobs", "total", netdata.IncrementalAlgorithm)
-			worker.AddChart(c, collector)
+	run(worker, *describe)
+}
 
-			c = netdata.NewChart(instance, "connections", "", "", "", tube, "beanstalk.connections")
-			c.AddDimension("_"+tube+"_current-using", "using", netdata.AbsoluteAlgorithm)
-			c.AddDimension("_"+tube+"_current-waiting", "waiting", netdata.AbsoluteAlgorithm)
-			c.AddDimension("_"+tube+"_current-watching", "watching", netdata.AbsoluteAlgorithm)
-			worker.AddChart(c, collector)
+// run prints the charts of the worker when describe is set, or runs it
+func run(worker netdata.Runner, describe string) {
+	if describe != "" {
+		if err := netdata.WriteDescription(os.Stdout, describe, worker.Describe()); err != nil {
+			log.Fatalln("ERROR: Beanstalk plugin: Could not describe charts", err)
 		}
+		os.Exit(0)
 	}
 
 	worker.Run()
 }
+
+// checkConfig validates the targets or the worker configuration, and connects
+// to each target
+func checkConfig(targets string, config string, relabel string) int {
+	check := util.NewCheck(os.Stdout)
+	if relabel != "" {
+		check.Run("relabel rules "+relabel, netdata.LoadRelabel(relabel))
+	}
+	if config != "" {
+		conf, err := netdata.LoadWorkerConfig(config)
+		if check.Run("worker configuration "+config, err) {
+			_, err = netdata.BuildWorker(conf, time.Second, netdata.NewDefaultWriter())
+			check.Run("collectors", err)
+			for _, c := range conf.Collectors {
+				if target, err := util.ParseTarget(c.Params["target"]); err == nil {
+					check.Target(target)
+				}
+			}
+		}
+		return check.Done()
+	}
+	if targets == "" {
+		check.Run("targets", fmt.Errorf("missing targets"))
+		return check.Done()
+	}
+	parsed, err := util.ParseTargets(targets)
+	if check.Run("targets", err) {
+		for _, target := range parsed {
+			check.Target(target)
+		}
+	}
+	return check.Done()
+}
@@ -18,6 +18,7 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"log"
 	"os"
 	"strings"
@@ -25,6 +26,7 @@ import (
 
 	"oionetdata/collector"
 	"oionetdata/command"
+	"oionetdata/logger"
 	"oionetdata/netdata"
 	"oionetdata/util"
 )
@@ -34,31 +36,84 @@ func main() {
 		log.Fatalf("argument required")
 	}
 	var conf string
+	var splay bool
+	var relabel string
 	fs := flag.NewFlagSet("", flag.ExitOnError)
 	fs.StringVar(&conf, "conf", "/etc/netdata/commands.conf", "Command configuration file")
+	fs.BoolVar(&splay, "splay", false, "Spread collections over the interval based on the host name")
+	fs.StringVar(&relabel, "relabel", "", "Path to the filter and relabel rules file")
+	check := util.CheckFlag(fs)
+	describe := netdata.DescribeFlag(fs)
+	logConf := logger.Flags(fs)
+	limits := netdata.LimitFlags(fs)
 	err := fs.Parse(os.Args[2:])
 	if err != nil {
 		log.Fatalln("ERROR: Command plugin: Could not parse args", err)
 	}
+	if err := logger.SetConfig(*logConf); err != nil {
+		log.Fatalln("ERROR: Command plugin: Invalid log configuration", err)
+	}
+	if *check {
+		os.Exit(checkConfig(conf, relabel))
+	}
+	if relabel != "" {
+		if err := netdata.LoadRelabel(relabel); err != nil {
+			log.Fatalln("ERROR: Command plugin: Could not load relabel rules", err)
+		}
+	}
 	intervalSeconds := collector.ParseIntervalSeconds(os.Args[1])
+	netdata.SetLimits(*limits)
 
-	cmds := util.Commands{}
-
-	if strings.HasSuffix(conf, ".yml") || strings.HasSuffix(conf, ".yaml") {
-		cmds, err = util.ParseCommandsYaml(conf)
-	} else {
-		cmds, err = util.ParseCommands(conf)
-	}
+	cmds, err := loadCommands(conf)
 	if err != nil {
 		log.Fatalln("ERROR: Command plugin: Could not load commands", err)
 	}
 
-	log.Printf("INFO: Command plugin: Loaded %d commands from %s", len(cmds.Config), conf)
+	logger.Info("Loaded commands", "count", len(cmds.Config), "conf", conf)
 
 	writer := netdata.NewDefaultWriter()
 	worker := netdata.NewWorker(time.Duration(intervalSeconds)*time.Second, writer)
+	if splay {
+		worker.EnableSplay()
+	}
 	collector := command.NewCollector(cmds.Config, int64(intervalSeconds), worker)
 	worker.SetCollector(collector)
 
+	if *describe != "" {
+		for _, chart := range command.Charts(cmds.Config) {
+			worker.AddChart(chart)
+		}
+		if err := netdata.WriteDescription(os.Stdout, *describe, worker.Describe()); err != nil {
+			log.Fatalln("ERROR: Command plugin: Could not describe charts", err)
+		}
+		os.Exit(0)
+	}
+
 	worker.Run()
 }
+
+// checkConfig validates the relabel rules and the commands file
+func checkConfig(conf string, relabel string) int {
+	check := util.NewCheck(os.Stdout)
+	if relabel != "" {
+		check.Run("relabel rules "+relabel, netdata.LoadRelabel(relabel))
+	}
+	cmds, err := loadCommands(conf)
+	if check.Run("commands file "+conf, err) {
+		for i, cmd := range cmds.Config {
+			err = nil
+			if cmd.Name == "" || cmd.Command == "" {
+				err = fmt.Errorf("name and command are required")
+			}
+			check.Run(fmt.Sprintf("command %d (%s)", i+1, cmd.Name), err)
+		}
+	}
+	return check.Done()
+}
+
+func loadCommands(conf string) (util.Commands, error) {
+	if strings.HasSuffix(conf, ".yml") || strings.HasSuffix(conf, ".yaml") {
+		return util.ParseCommandsYaml(conf)
+	}
+	return util.ParseCommands(conf)
+}
@@ -21,7 +21,9 @@ import (
 	"log"
 	"oionetdata/collector"
 	"oionetdata/container"
+	"oionetdata/logger"
 	"oionetdata/netdata"
+	"oionetdata/util"
 	"os"
 	"strings"
 
@@ -39,6 +41,8 @@ func main() {
 	var limit int64
 	var threshold int64
 	var fast bool
+	var splay bool
+	var relabel string
 
 	fs := flag.NewFlagSet("", flag.ExitOnError)
 	fs.StringVar(&ns, "ns", "OPENIO", "List of namespaces delimited by semicolons (:)")
@@ -47,14 +51,44 @@ func main() {
 	fs.Int64Var(&limit, "limit", -1, "Amount of processed containers in a single request, -1 for unlimited")
 	fs.Int64Var(&threshold, "threshold", 3e5, "Minimal number of objects in container to report it")
 	fs.BoolVar(&fast, "fast", false, "Use fast account listing")
+	fs.BoolVar(&splay, "splay", false, "Spread collections over the interval based on the host name")
+	fs.StringVar(&relabel, "relabel", "", "Path to the filter and relabel rules file")
+	fs.BoolVar(&util.Simulate, "simulate", false, "Produce synthetic values instead of querying services")
+	check := util.CheckFlag(fs)
+	describe := netdata.DescribeFlag(fs)
+	logConf := logger.Flags(fs)
+	limits := netdata.LimitFlags(fs)
 	err := fs.Parse(os.Args[2:])
 	if err != nil {
 		log.Fatalln("ERROR: Container plugin: Could not parse args", err)
 	}
+	if err := logger.SetConfig(*logConf); err != nil {
+		log.Fatalln("ERROR: Container plugin: Invalid log configuration", err)
+	}
+	if *check {
+		os.Exit(checkConfig(ns, conf, addr, relabel))
+	}
+	if relabel != "" {
+		if err := netdata.LoadRelabel(relabel); err != nil {
+			log.Fatalln("ERROR: Container plugin: Could not load relabel rules", err)
+		}
+	}
 	intervalSeconds := collector.ParseIntervalSeconds(os.Args[1])
+	netdata.SetLimits(*limits)
 
 	namespaces := strings.Split(ns, ":")
 	redisAddr := strings.Split(addr, ",")
+	if *describe != "" {
+		var patterns []netdata.LegacyPattern
+		for _, name := range namespaces {
+			patterns = append(patterns, container.Charts(name, fast)...)
+		}
+		if err := netdata.WriteDescription(os.Stdout, *describe, netdata.DescribeLegacy(patterns)); err != nil {
+			log.Fatalln("ERROR: Container plugin: Could not describe charts", err)
+		}
+		os.Exit(0)
+	}
+	collector.Splay = splay
 	collector.Run(intervalSeconds, makeCollect(conf, redisAddr, namespaces, limit, threshold, fast))
 }
 
@@ -64,6 +98,10 @@ func makeCollect(basePath string, addr, namespaces []string, l int64, t int64, f
 		errors := make(map[string]error)
 
 		for i, ns := range namespaces {
+			if util.Simulate {
+				container.Simulate(ns, f, c)
+				continue
+			}
 			redisAddr := ""
 			var err error
 			if i < len(addr) && addr[i] != "" {
@@ -85,3 +123,27 @@ func makeCollect(basePath string, addr, namespaces []string, l int64, t int64, f
 		return nil
 	}
 }
+
+// checkConfig validates the configuration and pings the redis of each namespace
+func checkConfig(ns string, conf string, addr string, relabel string) int {
+	check := util.NewCheck(os.Stdout)
+	if relabel != "" {
+		check.Run("relabel rules "+relabel, netdata.LoadRelabel(relabel))
+	}
+	forced := strings.Split(addr, ",")
+	for i, name := range strings.Split(ns, ":") {
+		redisAddr := ""
+		var err error
+		if i < len(forced) && forced[i] != "" {
+			redisAddr = forced[i]
+		} else {
+			redisAddr, err = container.RedisAddr(conf, name)
+		}
+		if check.Run("redis address of "+name, err) {
+			client := redis.NewClient(&redis.Options{Addr: redisAddr})
+			check.Run("ping redis "+redisAddr, client.Ping().Err())
+			client.Close()
+		}
+	}
+	return check.Done()
+}
@@ -25,6 +25,7 @@ import (
 	"time"
 
 	"oionetdata/collector"
+	"oionetdata/logger"
 	"oionetdata/netdata"
 	"oionetdata/oiofs"
 	"oionetdata/util"
@@ -36,19 +37,45 @@ func main() {
 	}
 	var conf string
 	var full bool
+	var splay bool
+	var relabel string
 	fs := flag.NewFlagSet("", flag.ExitOnError)
 	fs.StringVar(&conf, "conf", "/etc/netdata/oiofs.conf", "Path to endpoint config file")
 	fs.BoolVar(&full, "full", false, "Gather all metrics")
+	fs.BoolVar(&splay, "splay", false, "Spread collections over the interval based on the host name")
+	fs.StringVar(&relabel, "relabel", "", "Path to the filter and relabel rules file")
+	fs.BoolVar(&util.Simulate, "simulate", false, "Produce synthetic values instead of querying services")
+	check := util.CheckFlag(fs)
+	describe := netdata.DescribeFlag(fs)
+	logConf := logger.Flags(fs)
+	httpConf := util.HTTPFlags(fs)
 	err := fs.Parse(os.Args[2:])
 	if err != nil {
 		log.Fatalln("ERROR: Command plugin: Could not parse args", err)
 	}
+	if err := logger.SetConfig(*logConf); err != nil {
+		log.Fatalln("ERROR: Oiofs plugin: Invalid log configuration", err)
+	}
+	if *check {
+		os.Exit(checkConfig(conf, relabel, *httpConf))
+	}
+	if relabel != "" {
+		if err := netdata.LoadRelabel(relabel); err != nil {
+			log.Fatalln("ERROR: Oiofs plugin: Could not load relabel rules", err)
+		}
+	}
 	intervalSeconds := collector.ParseIntervalSeconds(os.Args[1])
 
+	if err := util.SetHTTPConfig(*httpConf); err != nil {
+		log.Fatalln("ERROR: Oiofs plugin: Invalid HTTP configuration", err)
+	}
+
 	var endpoints []oiofs.Endpoint
 
 	out, err := util.OiofsEndpoints(conf)
-	if err != nil {
+	if err != nil && util.Simulate {
+		out = map[string]string{"/mnt/simulated": "127.0.0.1:9000"}
+	} else if err != nil {
 		log.Fatalln("ERROR: Oiofs plugin: Could not load oiofs endpoints", err)
 	}
 
@@ -58,9 +85,15 @@ func main() {
 
 	writer := netdata.NewDefaultWriter()
 	worker := netdata.NewWorker(time.Duration(intervalSeconds)*time.Second, writer)
+	if splay {
+		worker.EnableSplay()
+	}
 
 	for _, endpoint := range endpoints {
-		collector := oiofs.NewCollector(endpoint, full)
+		var collector netdata.Collector = oiofs.NewCollector(endpoint, full)
+		if util.Simulate {
+			collector = oiofs.NewSimulatedCollector(endpoint, full)
+		}
 		worker.AddCollector(collector)
 		family := endpoint.Path
 		fsType := fmt.Sprintf("oiofs.%s", endpoint.Path)
@@ -188,5 +221,28 @@ func main() {
 		worker.AddChart(sdsData, collector)
 	}
 
+	if *describe != "" {
+		if err := netdata.WriteDescription(os.Stdout, *describe, worker.Describe()); err != nil {
+			log.Fatalln("ERROR: Oiofs plugin: Could not describe charts", err)
+		}
+		os.Exit(0)
+	}
+
 	worker.Run()
 }
+
+// checkConfig validates the configuration and queries each endpoint
+func checkConfig(conf string, relabel string, httpConf util.HTTPConfig) int {
+	check := util.NewCheck(os.Stdout)
+	if relabel != "" {
+		check.Run("relabel rules "+relabel, netdata.LoadRelabel(relabel))
+	}
+	check.Run("HTTP configuration", util.SetHTTPConfig(httpConf))
+	endpoints, err := util.OiofsEndpoints(conf)
+	if check.Run("endpoints file "+conf, err) {
+		for _, url := range endpoints {
+			check.HTTP(util.URL(url, "/stats"))
+		}
+	}
+	return check.Done()
+}
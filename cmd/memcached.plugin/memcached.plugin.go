@@ -18,12 +18,14 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"log"
 	"oionetdata/collector"
+	"oionetdata/logger"
 	"oionetdata/memcached"
 	"oionetdata/netdata"
+	"oionetdata/util"
 	"os"
-	"strings"
 	"time"
 )
 
@@ -32,113 +34,114 @@ func main() {
 		log.Fatalf("argument required")
 	}
 	var targets string
+	var splay bool
+	var relabel string
 	fs := flag.NewFlagSet("", flag.ExitOnError)
-	fs.StringVar(&targets, "targets", "", "Comma separated list of Redis IP:PORT")
+	fs.StringVar(&targets, "targets", "", util.TargetUsage)
+	fs.BoolVar(&splay, "splay", false, "Spread collections over the interval based on the host name")
+	fs.StringVar(&relabel, "relabel", "", "Path to the filter and relabel rules file")
+	fs.BoolVar(&util.Simulate, "simulate", false, "Produce synthetic values instead of querying services")
+	check := util.CheckFlag(fs)
+	describe := netdata.DescribeFlag(fs)
+	config := netdata.WorkerConfigFlag(fs)
+	logConf := logger.Flags(fs)
 	err := fs.Parse(os.Args[2:])
 	if err != nil {
 		log.Fatalln("ERROR: Memcached plugin: Could not parse args", err)
 	}
+	if err := logger.SetConfig(*logConf); err != nil {
+		log.Fatalln("ERROR: Memcached plugin: Invalid log configuration", err)
+	}
+	if *check {
+		os.Exit(checkConfig(targets, *config, relabel))
+	}
+	if relabel != "" {
+		if err := netdata.LoadRelabel(relabel); err != nil {
+			log.Fatalln("ERROR: Memcached plugin: Could not load relabel rules", err)
+		}
+	}
 	intervalSeconds := collector.ParseIntervalSeconds(os.Args[1])
+	interval := time.Duration(intervalSeconds) * time.Second
+	writer := netdata.NewDefaultWriter()
 
+	if *config != "" {
+		if targets != "" {
+			log.Fatalln("ERROR: Memcached plugin: --targets and --config are exclusive")
+		}
+		worker, err := netdata.LoadWorker(*config, interval, writer)
+		if err != nil {
+			log.Fatalln("ERROR: Memcached plugin: Could not load the worker configuration", err)
+		}
+		run(worker, *describe)
+		return
+	}
+
+	if targets == "" && util.Simulate {
+		targets = "tcp://127.0.0.1:11211?name=simulated"
+	}
 	if targets == "" {
 		log.Fatalln("ERROR: Memcached plugin: missing targets")
 	}
+	parsed, err := util.ParseTargets(targets)
+	if err != nil {
+		log.Fatalln("ERROR: Memcached plugin: invalid targets", err)
+	}
 
-	writer := netdata.NewDefaultWriter()
-	worker := netdata.NewWorker(time.Duration(intervalSeconds)*time.Second, writer)
+	worker := netdata.NewWorker(interval, writer)
+	if splay {
+		worker.EnableSplay()
+	}
 
-	for _, addr := range strings.Split(targets, ",") {
-		res := strings.Split(addr, ":")
-		if len(res) != 2 {
-			log.Fatalln("Invalid address", addr, "must be IP:PORT")
+	for _, target := range parsed {
+		if err := memcached.AddCharts(worker, target); err != nil {
+			log.Fatalln("ERROR: Memcached plugin:", err)
 		}
-		collector := memcached.NewCollector(res[0] + ":" + res[1])
-		worker.AddCollector(collector)
-		instance := "memcached." + addr
-
-		uptimeChart := netdata.NewChart(instance, "uptime", "", "Uptime", "seconds", instance, "memcached.uptime.")
-		uptimeChart.AddDimension("uptime", "current", netdata.AbsoluteAlgorithm)
-		worker.AddChart(uptimeChart, collector)
-
-		itemsChart := netdata.NewChart(instance, "items", "", "Items", "count", instance, "memcached.items.")
-		itemsChart.AddDimension("curr_items", "current", netdata.AbsoluteAlgorithm)
-		itemsChart.AddDimension("total_items", "total", netdata.IncrementalAlgorithm)
-		worker.AddChart(itemsChart, collector)
-
-		memChart := netdata.NewChart(instance, "memory", "", "Memory", "bytes", instance, "memcached.memory")
-		memChart.AddDimension("bytes", "current", netdata.AbsoluteAlgorithm)
-		memChart.AddDimension("limit_maxbytes", "max", netdata.AbsoluteAlgorithm)
-		worker.AddChart(memChart, collector)
-
-		connectionsChart := netdata.NewChart(instance, "connections", "", "Connections", "count", instance, "memcached.connections")
-		connectionsChart.AddDimension("max_connections", "max", netdata.AbsoluteAlgorithm)
-		connectionsChart.AddDimension("curr_connections", "current", netdata.AbsoluteAlgorithm)
-		connectionsChart.AddDimension("total_connections", "total", netdata.IncrementalAlgorithm)
-		connectionsChart.AddDimension("rejected_connections", "rejected", netdata.IncrementalAlgorithm)
-		connectionsChart.AddDimension("accepting_conns", "accepting", netdata.AbsoluteAlgorithm)
-		connectionsChart.AddDimension("listen_disabled_num", "disabled", netdata.AbsoluteAlgorithm)
-		connectionsChart.AddDimension("conn_yields", "yield", netdata.AbsoluteAlgorithm)
-		worker.AddChart(connectionsChart, collector)
-
-		reqsChart := netdata.NewChart(instance, "requests", "", "Requests", "requests", instance, "memcached.requests")
-		reqsChart.AddDimension("cmd_get", "get", netdata.IncrementalAlgorithm)
-		reqsChart.AddDimension("cmd_set", "set", netdata.IncrementalAlgorithm)
-		reqsChart.AddDimension("cmd_flush", "flush", netdata.IncrementalAlgorithm)
-		reqsChart.AddDimension("cmd_touch", "touch", netdata.IncrementalAlgorithm)
-		worker.AddChart(reqsChart, collector)
-
-		getsChart := netdata.NewChart(instance, "get_requests", "", "Get requests", "requests", instance, "memcached.get_requests")
-		getsChart.AddDimension("get_hits", "hits", netdata.IncrementalAlgorithm)
-		getsChart.AddDimension("get_misses", "misses", netdata.IncrementalAlgorithm)
-		getsChart.AddDimension("get_expired", "expired", netdata.IncrementalAlgorithm)
-		getsChart.AddDimension("get_flushed", "flushed", netdata.IncrementalAlgorithm)
-		worker.AddChart(getsChart, collector)
-
-		deletesChart := netdata.NewChart(instance, "delete_requests", "", "Delete requests", "requests", instance, "memcached.delete_requests")
-		deletesChart.AddDimension("delete_hits", "hits", netdata.IncrementalAlgorithm)
-		deletesChart.AddDimension("delete_misses", "misses", netdata.IncrementalAlgorithm)
-		worker.AddChart(deletesChart, collector)
-
-		incrsChart := netdata.NewChart(instance, "incr_requests", "", "Incr requests", "requests", instance, "memcached.incr_requests")
-		incrsChart.AddDimension("incr_hits", "hits", netdata.IncrementalAlgorithm)
-		incrsChart.AddDimension("incr_misses", "misses", netdata.IncrementalAlgorithm)
-		worker.AddChart(incrsChart, collector)
-
-		decrsChart := netdata.NewChart(instance, "decr_requests", "", "Decr requests", "requests", instance, "memcached.decr_requests")
-		decrsChart.AddDimension("decr_hits", "hits", netdata.IncrementalAlgorithm)
-		decrsChart.AddDimension("decr_misses", "misses", netdata.IncrementalAlgorithm)
-		worker.AddChart(decrsChart, collector)
-
-		cassChart := netdata.NewChart(instance, "cas_requests", "", "CAS requests", "requests", instance, "memcached.cas_requests")
-		cassChart.AddDimension("cas_hits", "hits", netdata.IncrementalAlgorithm)
-		cassChart.AddDimension("cas_misses", "misses", netdata.IncrementalAlgorithm)
-		cassChart.AddDimension("cas_bandval", "badval", netdata.IncrementalAlgorithm)
-		worker.AddChart(cassChart, collector)
-
-		touchsChart := netdata.NewChart(instance, "touch_requests", "", "Touch requests", "requests", instance, "memcached.touch_requests")
-		touchsChart.AddDimension("touch_hits", "hits", netdata.IncrementalAlgorithm)
-		touchsChart.AddDimension("touch_misses", "misses", netdata.IncrementalAlgorithm)
-		worker.AddChart(touchsChart, collector)
-
-		authsChart := netdata.NewChart(instance, "auth_requests", "", "Auth requests", "requests", instance, "memcached.auth_requests")
-		authsChart.AddDimension("auth_cmds", "total", netdata.IncrementalAlgorithm)
-		authsChart.AddDimension("auth_errors", "errors", netdata.IncrementalAlgorithm)
-		worker.AddChart(authsChart, collector)
+	}
 
-		netChart := netdata.NewChart(instance, "net", "", "Network", "bytes", instance, "memcached.net")
-		netChart.AddDimension("bytes_read", "in", netdata.IncrementalAlgorithm)
-		netChart.AddDimension("bytes_written", "out", netdata.IncrementalAlgorithm)
-		worker.AddChart(netChart, collector)
+	run(worker, *describe)
+}
 
-		lruChart := netdata.NewChart(instance, "lru", "", "LRU", "items", instance, "memcached.lru")
-		lruChart.AddDimension("expired_unfetched", "expired_unfetched", netdata.IncrementalAlgorithm)
-		lruChart.AddDimension("evicted_unfetched", "evicted_unfetched", netdata.IncrementalAlgorithm)
-		lruChart.AddDimension("evicted_active", "evicted_active", netdata.IncrementalAlgorithm)
-		lruChart.AddDimension("moves_to_cold", "moves_to_cold", netdata.IncrementalAlgorithm)
-		lruChart.AddDimension("moves_to_warm", "moves_to_warm", netdata.IncrementalAlgorithm)
-		lruChart.AddDimension("moves_within_lru", "moves_within_lru", netdata.IncrementalAlgorithm)
-		worker.AddChart(lruChart, collector)
+// run prints the charts of the worker when describe is set, or runs it
+func run(worker netdata.Runner, describe string) {
+	if describe != "" {
+		if err := netdata.WriteDescription(os.Stdout, describe, worker.Describe()); err != nil {
+			log.Fatalln("ERROR: Memcached plugin: Could not describe charts", err)
+		}
+		os.Exit(0)
 	}
 
 	worker.Run()
 }
+
+// checkConfig validates the targets or the worker configuration, and connects
+// to each target
+func checkConfig(targets string, config string, relabel string) int {
+	check := util.NewCheck(os.Stdout)
+	if relabel != "" {
+		check.Run("relabel rules "+relabel, netdata.LoadRelabel(relabel))
+	}
+	if config != "" {
+		conf, err := netdata.LoadWorkerConfig(config)
+		if check.Run("worker configuration "+config, err) {
+			_, err = netdata.BuildWorker(conf, time.Second, netdata.NewDefaultWriter())
+			check.Run("collectors", err)
+			for _, c := range conf.Collectors {
+				if target, err := util.ParseTarget(c.Params["target"]); err == nil {
+					check.Target(target)
+				}
+			}
+		}
+		return check.Done()
+	}
+	if targets == "" {
+		check.Run("targets", fmt.Errorf("missing targets"))
+		return check.Done()
+	}
+	parsed, err := util.ParseTargets(targets)
+	if check.Run("targets", err) {
+		for _, target := range parsed {
+			check.Target(target)
+		}
+	}
+	return check.Done()
+}
@@ -20,11 +20,13 @@ import (
 	"flag"
 	"log"
 	"oionetdata/collector"
+	"oionetdata/logger"
 	"oionetdata/netdata"
 	"oionetdata/openio"
 	"oionetdata/util"
 	"os"
 	"strings"
+	"time"
 )
 
 func main() {
@@ -35,37 +37,127 @@ func main() {
 	var ns string
 	var conf string
 	var remote bool
+	var splay bool
+	var relabel string
+	var cluster bool
+	var extraProxies string
 
 	fs := flag.NewFlagSet("", flag.ExitOnError)
 	fs.StringVar(&ns, "ns", "OPENIO", "List of namespaces delimited by semicolons (:)")
 	fs.StringVar(&conf, "conf", "/etc/oio/sds.conf.d/", "Path to SDS config")
+	fs.StringVar(&openio.SDSConf, "sds-conf", openio.SDSConf, "Path to the SDS service configurations, to find local services when the conscience is unreachable")
+	fs.StringVar(&extraProxies, "proxies", "", "Additional proxy endpoints IP:PORT delimited by commas, tried after those of the namespace configuration")
 	fs.BoolVar(&remote, "remote", false, "Force remote metric collection")
+	fs.BoolVar(&cluster, "cluster", false, "Report namespace aggregates per service type instead of local services, to run on a single node")
+	fs.BoolVar(&splay, "splay", false, "Spread collections over the interval based on the host name")
+	fs.StringVar(&relabel, "relabel", "", "Path to the filter and relabel rules file")
+	fs.BoolVar(&util.Simulate, "simulate", false, "Produce synthetic values instead of querying services")
+	check := util.CheckFlag(fs)
+	describe := netdata.DescribeFlag(fs)
+	logConf := logger.Flags(fs)
+	httpConf := util.HTTPFlags(fs)
+	limits := netdata.LimitFlags(fs)
 	err := fs.Parse(os.Args[2:])
 	if err != nil {
 		log.Fatalln("ERROR: OpenIO plugin: Could not parse args", err)
 	}
+	if err := logger.SetConfig(*logConf); err != nil {
+		log.Fatalln("ERROR: OpenIO plugin: Invalid log configuration", err)
+	}
+	if *check {
+		os.Exit(checkConfig(ns, conf, extraProxies, relabel, *httpConf))
+	}
+	if relabel != "" {
+		if err := netdata.LoadRelabel(relabel); err != nil {
+			log.Fatalln("ERROR: OpenIO plugin: Could not load relabel rules", err)
+		}
+	}
 	interval := collector.ParseIntervalSeconds(os.Args[1])
+	netdata.SetLimits(*limits)
+
+	if err := util.SetHTTPConfig(*httpConf); err != nil {
+		log.Fatalln("ERROR: OpenIO plugin: Invalid HTTP configuration", err)
+	}
+
+	if *describe != "" {
+		var patterns []netdata.LegacyPattern
+		for _, name := range strings.Split(ns, ":") {
+			if cluster {
+				patterns = append(patterns, openio.ClusterCharts(name)...)
+			} else {
+				patterns = append(patterns, openio.Charts(name)...)
+			}
+		}
+		if err := netdata.WriteDescription(os.Stdout, *describe, netdata.DescribeLegacy(patterns)); err != nil {
+			log.Fatalln("ERROR: OpenIO plugin: Could not describe charts", err)
+		}
+		os.Exit(0)
+	}
 
 	util.ForceRemote = remote
-	openio.CollectInterval = int(interval)
-	var proxyURLs = make(map[string]string)
+	var proxies = make(map[string]*openio.Proxies)
 	namespaces := strings.Split(ns, ":")
 	for _, name := range namespaces {
-		addr, err := openio.ProxyAddr(conf, name)
-		if err != nil {
+		addrs, err := proxyAddrs(conf, name, extraProxies)
+		if err != nil && !util.Simulate {
 			log.Fatalf("Load failure: %v", err)
 		}
-		proxyURLs[name] = addr
+		proxies[name] = openio.NewProxies(name, addrs)
 	}
 
-	collector.Run(interval, makeCollect(proxyURLs))
+	// Counters saved on reload are only reused if the plugin restarts promptly
+	store := util.NewStateStore("openio.plugin", time.Duration(3*interval)*time.Second)
+	collector.Splay = splay
+	collector.Run(interval, makeCollect(proxies, cluster), openio.NewState(store))
 }
 
-func makeCollect(proxyURLs map[string]string) (collect collector.Collect) {
+func makeCollect(proxies map[string]*openio.Proxies, cluster bool) (collect collector.Collect) {
 	return func(c chan netdata.Metric) error {
-		for ns, proxyURL := range proxyURLs {
-			openio.Collect(proxyURL, ns, c)
+		for ns, p := range proxies {
+			switch {
+			case util.Simulate && cluster:
+				openio.SimulateCluster(ns, c)
+			case util.Simulate:
+				openio.Simulate(ns, c)
+			case cluster:
+				openio.CollectClusterProxies(p, ns, c)
+			default:
+				openio.CollectProxies(p, ns, c)
+			}
 		}
 		return nil
 	}
 }
+
+// proxyAddrs returns the proxies of the namespace configuration followed by
+// the extra endpoints; the configuration may lack a proxy if extra ones are given
+func proxyAddrs(conf string, ns string, extra string) ([]string, error) {
+	addrs, err := openio.ProxyAddrs(conf, ns)
+	for _, addr := range strings.Split(extra, ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			addrs = append(addrs, addr)
+		}
+	}
+	if len(addrs) > 0 {
+		return addrs, nil
+	}
+	return nil, err
+}
+
+// checkConfig validates the configuration and reaches the proxies of each namespace
+func checkConfig(ns string, conf string, extraProxies string, relabel string, httpConf util.HTTPConfig) int {
+	check := util.NewCheck(os.Stdout)
+	if relabel != "" {
+		check.Run("relabel rules "+relabel, netdata.LoadRelabel(relabel))
+	}
+	check.Run("HTTP configuration", util.SetHTTPConfig(httpConf))
+	for _, name := range strings.Split(ns, ":") {
+		addrs, err := proxyAddrs(conf, name, extraProxies)
+		if check.Run("proxy addresses of "+name, err) {
+			for _, addr := range addrs {
+				check.Run("conscience of "+name+" through "+addr, openio.CheckProxy(addr, name))
+			}
+		}
+	}
+	return check.Done()
+}
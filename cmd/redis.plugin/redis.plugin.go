@@ -21,10 +21,11 @@ import (
 	"fmt"
 	"log"
 	"oionetdata/collector"
+	"oionetdata/logger"
 	"oionetdata/netdata"
 	"oionetdata/redis"
+	"oionetdata/util"
 	"os"
-	"strings"
 	"time"
 )
 
@@ -33,90 +34,114 @@ func main() {
 		log.Fatalf("argument required")
 	}
 	var targets string
+	var splay bool
+	var relabel string
 	fs := flag.NewFlagSet("", flag.ExitOnError)
-	fs.StringVar(&targets, "targets", "", "Comma separated list of Redis IP:PORT:CLUSTER_ID")
+	fs.StringVar(&targets, "targets", "", util.TargetUsage+"&cluster=CLUSTER_ID (legacy: IP:PORT:CLUSTER_ID)")
+	fs.BoolVar(&splay, "splay", false, "Spread collections over the interval based on the host name")
+	fs.StringVar(&relabel, "relabel", "", "Path to the filter and relabel rules file")
+	fs.BoolVar(&util.Simulate, "simulate", false, "Produce synthetic values instead of querying services")
+	check := util.CheckFlag(fs)
+	describe := netdata.DescribeFlag(fs)
+	config := netdata.WorkerConfigFlag(fs)
+	logConf := logger.Flags(fs)
 	err := fs.Parse(os.Args[2:])
 	if err != nil {
 		log.Fatalln("ERROR: Redis plugin: Could not parse args", err)
 	}
+	if err := logger.SetConfig(*logConf); err != nil {
+		log.Fatalln("ERROR: Redis plugin: Invalid log configuration", err)
+	}
+	if *check {
+		os.Exit(checkConfig(targets, *config, relabel))
+	}
+	if relabel != "" {
+		if err := netdata.LoadRelabel(relabel); err != nil {
+			log.Fatalln("ERROR: Redis plugin: Could not load relabel rules", err)
+		}
+	}
 	intervalSeconds := collector.ParseIntervalSeconds(os.Args[1])
+	interval := time.Duration(intervalSeconds) * time.Second
+	writer := netdata.NewDefaultWriter()
 
+	if *config != "" {
+		if targets != "" {
+			log.Fatalln("ERROR: Redis plugin: --targets and --config are exclusive")
+		}
+		worker, err := netdata.LoadWorker(*config, interval, writer)
+		if err != nil {
+			log.Fatalln("ERROR: Redis plugin: Could not load the worker configuration", err)
+		}
+		run(worker, *describe)
+		return
+	}
+
+	if targets == "" && util.Simulate {
+		targets = "tcp://127.0.0.1:6379?name=simulated"
+	}
 	if targets == "" {
 		log.Fatalln("ERROR: Redis plugin: missing targets")
 	}
+	parsed, err := util.ParseTargets(targets)
+	if err != nil {
+		log.Fatalln("ERROR: Redis plugin: invalid targets", err)
+	}
 
+	worker := netdata.NewWorker(interval, writer)
+	if splay {
+		worker.EnableSplay()
+	}
 
-
-	writer := netdata.NewDefaultWriter()
-	worker := netdata.NewWorker(time.Duration(intervalSeconds)*time.Second, writer)
-
-	for _, addr := range strings.Split(targets, ",") {
-		res := strings.Split(addr, ":")
-		if len(res) != 3 {
-			// CLUSTER_ID is used exclusively as a label here; it allows to group metrics by cluster to provide
-			// alerts when the cluster size/state is incorrect
-			log.Fatalln("Invalid address", addr, "must be IP:PORT:CLUSTER_ID")
+	for _, target := range parsed {
+		if err := redis.AddCharts(worker, target); err != nil {
+			log.Fatalln("ERROR: Redis plugin:", err)
 		}
-		collector := redis.NewCollector(res[0] + ":" + res[1])
-		worker.AddCollector(collector)
-		instance := "redis." + addr
-
-		keysChart := netdata.NewChart(instance, "keys", "", "Keys", "count", instance, "redis.keys.")
-		keysChart.AddDimension(
-			fmt.Sprintf("keys"),
-			"keys",
-			netdata.AbsoluteAlgorithm,
-		)
-		worker.AddChart(keysChart, collector)
-
-		memChart := netdata.NewChart(instance, "memory", "", "Memory", "bytes", instance, "redis.memory")
-		memChart.AddDimension("used_memory", "total", netdata.AbsoluteAlgorithm)
-		memChart.AddDimension("used_memory_rss", "rss", netdata.AbsoluteAlgorithm)
-		memChart.AddDimension("used_memory_lua", "lua", netdata.AbsoluteAlgorithm)
-		worker.AddChart(memChart, collector)
-
-		bandwidthChart := netdata.NewChart(instance, "net", "", "Network traffic", "bytes", instance, "redis.net")
-		bandwidthChart.AddDimension("total_net_input_bytes", "received", netdata.IncrementalAlgorithm)
-		bandwidthChart.AddDimension("total_net_output_bytes", "sent", netdata.IncrementalAlgorithm)
-		worker.AddChart(bandwidthChart, collector)
-
-		opsChart := netdata.NewChart(instance, "instant", "", "Instantaneous operations", "ops", instance, "redis.ops")
-		opsChart.AddDimension("instantaneous_ops_per_sec", "ops", netdata.AbsoluteAlgorithm)
-		worker.AddChart(opsChart, collector)
-
-		masterChart := netdata.NewChart(instance, "state", "", "Instance is master", "master", instance, "redis.master")
-		masterChart.AddDimension("is_master", "state", netdata.AbsoluteAlgorithm)
-		worker.AddChart(masterChart, collector)
-
-		replicaCharts := netdata.NewChart(instance, "replicas", "", "Replicas", "count", instance, "redis.replicas")
-		replicaCharts.AddDimension("connected_slaves", "replicas", netdata.AbsoluteAlgorithm)
-		worker.AddChart(replicaCharts, collector)
-
-		cacheCharts := netdata.NewChart(instance, "cache", "", "Cache", "ops", instance, "redis.cache")
-		cacheCharts.AddDimension("keyspace_hits", "hits", netdata.AbsoluteAlgorithm)
-		cacheCharts.AddDimension("keyspace_misses", "misses", netdata.AbsoluteAlgorithm)
-		worker.AddChart(cacheCharts, collector)
-
-		backlogCharts := netdata.NewChart(instance, "backlog", "", "Backlog", "bytes", instance, "redis.backlog")
-		backlogCharts.AddDimension("repl_backlog_size", "backlog", netdata.AbsoluteAlgorithm)
-		worker.AddChart(backlogCharts, collector)
-
-		changesSinceSave := netdata.NewChart(instance, "changes", "", "Changes since last save", "ops", instance, "redis.changes")
-		changesSinceSave.AddDimension("rdb_changes_since_last_save", "changes", netdata.AbsoluteAlgorithm)
-		worker.AddChart(changesSinceSave, collector)
-
-		connCharts := netdata.NewChart(instance, "connections", "", "Connections", "count", instance, "redis.connections")
-		connCharts.AddDimension("total_connections_received", "connections", netdata.AbsoluteAlgorithm)
-		worker.AddChart(connCharts, collector)
+	}
 
-		commandCharts := netdata.NewChart(instance, "commands", "", "Commands", "count", instance, "redis.commands")
-		commandCharts.AddDimension("total_commands_processed", "commands", netdata.AbsoluteAlgorithm)
-		worker.AddChart(commandCharts, collector)
+	run(worker, *describe)
+}
 
-		memFragmentCharts := netdata.NewChart(instance, "fragmentation", "", "Memory fragmentation", "ratio", instance, "redis.fragmentation")
-		memFragmentCharts.AddDimension("mem_fragmentation_ratio", "fragmentation", netdata.AbsoluteAlgorithm)
-		worker.AddChart(memFragmentCharts, collector)
+// run prints the charts of the worker when describe is set, or runs it
+func run(worker netdata.Runner, describe string) {
+	if describe != "" {
+		if err := netdata.WriteDescription(os.Stdout, describe, worker.Describe()); err != nil {
+			log.Fatalln("ERROR: Redis plugin: Could not describe charts", err)
+		}
+		os.Exit(0)
 	}
 
 	worker.Run()
 }
+
+// checkConfig validates the targets or the worker configuration, and connects
+// to each target
+func checkConfig(targets string, config string, relabel string) int {
+	check := util.NewCheck(os.Stdout)
+	if relabel != "" {
+		check.Run("relabel rules "+relabel, netdata.LoadRelabel(relabel))
+	}
+	if config != "" {
+		conf, err := netdata.LoadWorkerConfig(config)
+		if check.Run("worker configuration "+config, err) {
+			_, err = netdata.BuildWorker(conf, time.Second, netdata.NewDefaultWriter())
+			check.Run("collectors", err)
+			for _, c := range conf.Collectors {
+				if target, err := util.ParseTarget(c.Params["target"]); err == nil {
+					check.Target(target)
+				}
+			}
+		}
+		return check.Done()
+	}
+	if targets == "" {
+		check.Run("targets", fmt.Errorf("missing targets"))
+		return check.Done()
+	}
+	parsed, err := util.ParseTargets(targets)
+	if check.Run("targets", err) {
+		for _, target := range parsed {
+			check.Target(target)
+		}
+	}
+	return check.Done()
+}
@@ -20,10 +20,13 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"net"
+	"net/url"
 	"os"
 	"time"
 
 	"oionetdata/collector"
+	"oionetdata/logger"
 	"oionetdata/netdata"
 	"oionetdata/s3roundtrip"
 	"oionetdata/util"
@@ -36,26 +39,57 @@ func main() {
 		log.Fatalf("argument required")
 	}
 	var conf string
+	var splay bool
+	var relabel string
 	fs := flag.NewFlagSet("", flag.ExitOnError)
 	fs.StringVar(&conf, "conf", "/etc/netdata/s3-roundtrip.conf", "Path to roundtrip config file")
+	fs.BoolVar(&splay, "splay", false, "Spread collections over the interval based on the host name")
+	fs.StringVar(&relabel, "relabel", "", "Path to the filter and relabel rules file")
+	fs.BoolVar(&util.Simulate, "simulate", false, "Produce synthetic values instead of querying services")
+	check := util.CheckFlag(fs)
+	describe := netdata.DescribeFlag(fs)
+	logConf := logger.Flags(fs)
 	err := fs.Parse(os.Args[2:])
 	if err != nil {
 		log.Fatalln("ERROR: S3Roundtrip plugin: Could not parse args", err)
 	}
+	if err := logger.SetConfig(*logConf); err != nil {
+		log.Fatalln("ERROR: S3Roundtrip plugin: Invalid log configuration", err)
+	}
+	if *check {
+		os.Exit(checkConfig(conf, relabel))
+	}
+	if relabel != "" {
+		if err := netdata.LoadRelabel(relabel); err != nil {
+			log.Fatalln("ERROR: S3Roundtrip plugin: Could not load relabel rules", err)
+		}
+	}
 	intervalSeconds := collector.ParseIntervalSeconds(os.Args[1])
 
 	writer := netdata.NewDefaultWriter()
 	worker := netdata.NewWorker(time.Duration(intervalSeconds)*time.Second, writer)
+	if splay {
+		worker.EnableSplay()
+	}
 
+	var collector netdata.Collector
+	var endpoint string
 	config, err := util.S3RoundtripConfig(conf)
-	if err != nil {
+	if util.Simulate {
+		endpoint = config["endpoint"]
+		if endpoint == "" {
+			endpoint = "http://localhost:6007"
+		}
+		collector = s3roundtrip.NewSimulatedCollector(endpoint, requests)
+	} else if err != nil {
 		log.Fatalln("Could not parse configuration file", err)
+	} else {
+		roundtrip := s3roundtrip.NewCollector(config, requests)
+		collector, endpoint = roundtrip, roundtrip.Endpoint
 	}
-
-	collector := s3roundtrip.NewCollector(config, requests)
 	worker.AddCollector(collector)
 
-	responseCode := netdata.NewChart("roundtrip", "response_code", "", "Response code", "ops", collector.Endpoint, "")
+	responseCode := netdata.NewChart("roundtrip", "response_code", "", "Response code", "ops", endpoint, "")
 	for _, req := range requests {
 		for _, dim := range []string{"2xx", "4xx", "5xx", "other"} {
 			dimension := fmt.Sprintf("response_code_%s_%s", req, dim)
@@ -64,17 +98,47 @@ func main() {
 	}
 	worker.AddChart(responseCode, collector)
 
-	responseTime := netdata.NewChart("roundtrip", "response_time", "", "Response time", "ms", collector.Endpoint, "")
+	responseTime := netdata.NewChart("roundtrip", "response_time", "", "Response time", "ms", endpoint, "")
 	for _, req := range requests {
 		dimension := fmt.Sprintf("response_time_%s", req)
 		responseTime.AddDimension(dimension, dimension, netdata.AbsoluteAlgorithm)
 	}
 	worker.AddChart(responseTime, collector)
 
-	ttfb := netdata.NewChart("roundtrip", "ttfb", "", "Time to first byte", "ms", collector.Endpoint, "")
+	ttfb := netdata.NewChart("roundtrip", "ttfb", "", "Time to first byte", "ms", endpoint, "")
 	ttfb.AddDimension("ttfb_put", "ttfb_put", netdata.AbsoluteAlgorithm)
 	ttfb.AddDimension("ttfb_get", "ttfb_get", netdata.AbsoluteAlgorithm)
 	worker.AddChart(ttfb, collector)
 
+	if *describe != "" {
+		if err := netdata.WriteDescription(os.Stdout, *describe, worker.Describe()); err != nil {
+			log.Fatalln("ERROR: S3Roundtrip plugin: Could not describe charts", err)
+		}
+		os.Exit(0)
+	}
+
 	worker.Run()
 }
+
+// checkConfig validates the configuration and connects to the endpoint
+func checkConfig(conf string, relabel string) int {
+	check := util.NewCheck(os.Stdout)
+	if relabel != "" {
+		check.Run("relabel rules "+relabel, netdata.LoadRelabel(relabel))
+	}
+	config, err := util.S3RoundtripConfig(conf)
+	if check.Run("configuration file "+conf, err) && check.Run("configuration keys", s3roundtrip.CheckConfig(config)) {
+		endpoint, err := url.Parse(config["endpoint"])
+		if check.Run("endpoint "+config["endpoint"], err) {
+			port := endpoint.Port()
+			if port == "" {
+				port = "80"
+				if endpoint.Scheme == "https" {
+					port = "443"
+				}
+			}
+			check.Addr(net.JoinHostPort(endpoint.Hostname(), port))
+		}
+	}
+	return check.Done()
+}
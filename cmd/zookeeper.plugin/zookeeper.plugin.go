@@ -21,12 +21,13 @@ import (
 	"fmt"
 	"log"
 	"os"
-	"strings"
 	"time"
 
 	"oionetdata/collector"
+	"oionetdata/logger"
 	"oionetdata/netdata"
 	"oionetdata/openio"
+	"oionetdata/util"
 	"oionetdata/zookeeper"
 )
 
@@ -36,28 +37,56 @@ func main() {
 	}
 	var ns string
 	var conf string
+	var splay bool
+	var relabel string
 	fs := flag.NewFlagSet("", flag.ExitOnError)
 	fs.StringVar(&ns, "ns", "OPENIO", "Namespace")
 	fs.StringVar(&conf, "conf", "/etc/oio/sds.conf.d/", "Path to SDS config")
+	fs.BoolVar(&splay, "splay", false, "Spread collections over the interval based on the host name")
+	fs.StringVar(&relabel, "relabel", "", "Path to the filter and relabel rules file")
+	fs.BoolVar(&util.Simulate, "simulate", false, "Produce synthetic values instead of querying services")
+	check := util.CheckFlag(fs)
+	describe := netdata.DescribeFlag(fs)
+	logConf := logger.Flags(fs)
 	err := fs.Parse(os.Args[2:])
 	if err != nil {
 		log.Fatalln("ERROR: Zookeeper plugin: Could not parse args", err)
 	}
+	if err := logger.SetConfig(*logConf); err != nil {
+		log.Fatalln("ERROR: Zookeeper plugin: Invalid log configuration", err)
+	}
+	if *check {
+		os.Exit(checkConfig(ns, conf, relabel))
+	}
+	if relabel != "" {
+		if err := netdata.LoadRelabel(relabel); err != nil {
+			log.Fatalln("ERROR: Zookeeper plugin: Could not load relabel rules", err)
+		}
+	}
 	intervalSeconds := collector.ParseIntervalSeconds(os.Args[1])
 
 	addr, err := openio.ZookeeperAddr(conf, ns)
-	if err != nil {
+	if err != nil && util.Simulate {
+		addr = "127.0.0.1:6005"
+	} else if err != nil {
 		log.Fatalf("Load failure: %v", err)
 	}
 	writer := netdata.NewDefaultWriter()
-	collector := zookeeper.NewCollector(addr)
+	var collector netdata.Collector = zookeeper.NewCollector(addr)
+	if util.Simulate {
+		collector = zookeeper.NewSimulatedCollector(util.Target{Network: "tcp", Addr: addr, Name: addr})
+	}
 	worker := netdata.NewWorker(time.Duration(intervalSeconds)*time.Second, writer, collector)
+	if splay {
+		worker.EnableSplay()
+	}
 
-	fAddr := strings.Replace(addr, ".", "_", -1)
-	fAddr = strings.Replace(fAddr, ":", "_", -1)
-	zkType := fmt.Sprintf("zk_%s_%s", ns, fAddr)
+	zkType := fmt.Sprintf("zk_%s_%s", ns, util.AddrID(addr))
 	family := "zookeeper"
 
+	// Availability
+	worker.AddTarget(collector, zkType, family, "zk")
+
 	// Latency
 	latencyStats := netdata.NewChart(zkType, "latency", "", "Latency Stats", "microseconds", family, "zk.latency")
 	latencyStats.AddDimension("zk_min_latency", "min", netdata.AbsoluteAlgorithm)
@@ -104,5 +133,25 @@ func main() {
 	syncStats.AddDimension("zk_pending_syncs", "syncs", netdata.AbsoluteAlgorithm)
 	worker.AddChart(syncStats)
 
+	if *describe != "" {
+		if err := netdata.WriteDescription(os.Stdout, *describe, worker.Describe()); err != nil {
+			log.Fatalln("ERROR: Zookeeper plugin: Could not describe charts", err)
+		}
+		os.Exit(0)
+	}
+
 	worker.Run()
 }
+
+// checkConfig validates the configuration and connects to the local zookeeper
+func checkConfig(ns string, conf string, relabel string) int {
+	check := util.NewCheck(os.Stdout)
+	if relabel != "" {
+		check.Run("relabel rules "+relabel, netdata.LoadRelabel(relabel))
+	}
+	addr, err := openio.ZookeeperAddr(conf, ns)
+	if check.Run("zookeeper address of "+ns, err) {
+		check.Addr(addr)
+	}
+	return check.Done()
+}
@@ -17,10 +17,12 @@
 package collector
 
 import (
-	"fmt"
-	"log"
+	"oionetdata/logger"
 	"oionetdata/netdata"
+	"os"
+	"os/signal"
 	"strconv"
+	"syscall"
 	"time"
 )
 
@@ -28,13 +30,20 @@ import (
 // Restarting the collector every now and then should help getting rid of memleaks
 var PollsBeforeReload = 1000
 
-var buf = make(map[string][]byte)
+// Splay -- align collections on slots shifted by a per host offset
+var Splay = false
 
 // Collect -- function to call on each collection
 type Collect func(chan netdata.Metric) error
 
 const DefaultIntervalSeconds = 10
 
+// State -- hooks to restore a collector state on start and checkpoint it on reload
+type State interface {
+	Load() error
+	Save() error
+}
+
 // ParseIntervalSeconds parses the interval
 func ParseIntervalSeconds(arg string) int {
 	interval, err := strconv.Atoi(arg)
@@ -44,36 +53,76 @@ func ParseIntervalSeconds(arg string) int {
 	return interval
 }
 
-// Run -- run the collector
-func Run(intervalSeconds int, collect Collect) {
+// Run -- run the collector. On SIGTERM or SIGINT, it stops starting cycles,
+// writes the metrics queued so far and saves the states before returning
+func Run(intervalSeconds int, collect Collect, states ...State) {
+	for _, state := range states {
+		if err := state.Load(); err != nil {
+			logger.Warn("Failed to restore collector state", "err", err)
+		}
+	}
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGTERM, syscall.SIGINT)
+	defer signal.Stop(sig)
+	// wait sleeps for d and returns false if a stop signal arrived meanwhile
+	wait := func(d time.Duration) bool {
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		select {
+		case s := <-sig:
+			logger.Info("Stopping collector", "signal", s)
+			return false
+		case <-timer.C:
+			return true
+		}
+	}
+
 	poll := 0
 	cd := 1
 	maxCd := intervalSeconds * 20
 
-	for poll < PollsBeforeReload {
-		c := make(chan netdata.Metric, 1e5)
+	// The channel outlives a single cycle: metrics sent by goroutines that
+	// are still running when the cycle ends are written on the next one
+	c := make(chan netdata.Metric, 1e5)
+	out := netdata.NewDefaultWriter()
+
+	interval := time.Duration(intervalSeconds) * time.Second
+	offset := netdata.SplayOffset(interval)
+	running := true
+	if Splay {
+		logger.Info("Splay offset", "offset", offset)
+		running = wait(netdata.UntilNextSlot(time.Now(), interval, offset))
+	}
+
+	for running && poll < PollsBeforeReload {
 		err := collect(c)
 		if err != nil {
 			cd += cd
 			if cd > maxCd {
 				cd = maxCd
 			}
-			log.Println("Collect function returned an error", err)
-			log.Printf("Retrying collection in %d second(s)", cd)
-			time.Sleep(time.Duration(cd) * time.Second)
+			logger.Warn("Collect function returned an error", "err", err, "retry_in", time.Duration(cd)*time.Second)
+			running = wait(time.Duration(cd) * time.Second)
 		} else {
 			cd = 1
 		}
-		time.Sleep(time.Duration(intervalSeconds) * time.Second)
-		close(c)
-		for m := range c {
-			buf[m.Chart] = append(buf[m.Chart], fmt.Sprintf("SET %s %s\n", m.Dim, m.Value)...)
+		if running && Splay {
+			running = wait(netdata.UntilNextSlot(time.Now(), interval, offset))
+		} else if running {
+			running = wait(interval)
 		}
-		for c, v := range buf {
-			fmt.Printf("BEGIN %s\n%sEND\n", c, string(v))
+		if err := netdata.Flush(c, out); err != nil {
+			logger.Error("Failed to write metrics", "err", err)
 		}
 		poll++
-		// Send & reset the buffer after the collection
-		buf = make(map[string][]byte)
+	}
+	saveStates(states)
+}
+
+func saveStates(states []State) {
+	for _, state := range states {
+		if err := state.Save(); err != nil {
+			logger.Warn("Failed to save collector state", "err", err)
+		}
 	}
 }
@@ -0,0 +1,78 @@
+// OpenIO netdata collectors
+// Copyright (C) 2019 OpenIO SAS
+//
+// This library is free software; you can redistribute it and/or
+// modify it under the terms of the GNU Lesser General Public
+// License as published by the Free Software Foundation; either
+// version 3.0 of the License, or (at your option) any later version.
+//
+// This library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package collector
+
+import (
+	"io/ioutil"
+	"oionetdata/netdata"
+	"oionetdata/util"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+type testState struct {
+	store *util.StateStore
+}
+
+func (s *testState) Load() error {
+	return nil
+}
+
+func (s *testState) Save() error {
+	return s.store.Save(map[string]int{"cycles": 1})
+}
+
+func TestRunSignal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "collector")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.Setenv("NETDATA_CACHE_DIR", dir)
+	defer os.Unsetenv("NETDATA_CACHE_DIR")
+
+	started := make(chan struct{})
+	collect := func(c chan netdata.Metric) error {
+		select {
+		case <-started:
+		default:
+			close(started)
+		}
+		return nil
+	}
+	done := make(chan struct{})
+	go func() {
+		Run(3600, collect, &testState{util.NewStateStore("collector.test", time.Minute)})
+		close(done)
+	}()
+
+	<-started
+	if err := syscall.Kill(os.Getpid(), syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Run did not return on SIGTERM")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "collector.test.state.json")); err != nil {
+		t.Fatalf("state not saved: %v", err)
+	}
+}
@@ -19,6 +19,7 @@ package command
 import (
 	"fmt"
 	"log"
+	"oionetdata/logger"
 	"oionetdata/netdata"
 	"oionetdata/util"
 	"os/exec"
@@ -80,11 +81,11 @@ func (c *collector) Collect() (map[string]string, error) {
 		value, err := c.runCommand(cmd.Command)
 
 		if err != nil {
-			log.Printf("WARN: Command collector: command %s failed with error %s", cmd.Command, err)
+			logger.Warn("Command failed", "command", cmd.Command, "err", err)
 			continue
 		}
 		if value == "" {
-			log.Printf("WARN: Command collector: command %s returned no output", cmd.Command)
+			logger.Warn("Command returned no output", "command", cmd.Command)
 			continue
 		}
 
@@ -112,9 +113,7 @@ func (c *collector) Collect() (map[string]string, error) {
 
 		// Check if a new chart needs to be created
 		if _, ok := c.cache[chart]; !ok {
-			newChart := netdata.NewChart(chart, cmd.Name, "", cmd.Name, "", cmd.Family, "command")
-			newChart.AddDimension(chart, cmd.Name, netdata.AbsoluteAlgorithm)
-			c.worker.AddChart(newChart)
+			c.worker.AddChart(newChart(cmd, chart))
 			c.cache[chart] = true
 		}
 		if cmd.ValueIsLabel || valueAsLabel {
@@ -125,6 +124,26 @@ func (c *collector) Collect() (map[string]string, error) {
 	return c.data, nil
 }
 
+func newChart(cmd util.Command, chart string) *netdata.Chart {
+	newChart := netdata.NewChart(chart, cmd.Name, "", cmd.Name, "", cmd.Family, "command")
+	newChart.AddDimension(chart, cmd.Name, netdata.AbsoluteAlgorithm)
+	return newChart
+}
+
+// Charts returns the charts created for the commands. Values that are not
+// numbers are reported as labels, with one chart per value
+func Charts(cmds []util.Command) []*netdata.Chart {
+	var charts []*netdata.Chart
+	for _, cmd := range cmds {
+		chart := fmt.Sprintf("cmd_%s", cmd.Name)
+		if cmd.ValueIsLabel {
+			chart += "_<value>"
+		}
+		charts = append(charts, newChart(cmd, chart))
+	}
+	return charts
+}
+
 func (c *collector) runCommand(cmd string) (string, error) {
 	out, err := exec.Command("/bin/bash", "-c", cmd).Output()
 	if err != nil {
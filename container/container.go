@@ -19,6 +19,7 @@ package container
 import (
 	"encoding/json"
 	"fmt"
+	"net"
 	"oionetdata/netdata"
 	"oionetdata/util"
 	"path"
@@ -87,7 +88,7 @@ func RedisAddr(basePath string, ns string) (string, error) {
 	ip := conf["bind"]
 	port := conf["port"]
 	if len(ip) != 0 && len(port) != 0 {
-		return fmt.Sprintf("%s:%s", ip, port), nil
+		return net.JoinHostPort(ip, port), nil
 	}
 	return "", fmt.Errorf("invalid redis conf")
 }
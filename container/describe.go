@@ -0,0 +1,42 @@
+// OpenIO netdata collectors
+// Copyright (C) 2019 OpenIO SAS
+//
+// This library is free software; you can redistribute it and/or
+// modify it under the terms of the GNU Lesser General Public
+// License as published by the Free Software Foundation; either
+// version 3.0 of the License, or (at your option) any later version.
+//
+// This library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package container
+
+import (
+	"oionetdata/netdata"
+)
+
+/*
+Charts - patterns of the charts sent by Collect for a namespace, fast mode
+reports accounts instead of containers
+*/
+func Charts(ns string, fast bool) []netdata.LegacyPattern {
+	account := ns + ".<account>"
+	if fast {
+		return []netdata.LegacyPattern{
+			{Chart: "account_bytes", Dimension: account},
+			{Chart: "account_kilobytes", Dimension: account},
+			{Chart: "account_objects", Dimension: account},
+			{Chart: "container_count", Dimension: account},
+		}
+	}
+	return []netdata.LegacyPattern{
+		{Chart: "container_count", Dimension: account},
+		{Chart: "container_objects", Dimension: account + ".<container>"},
+		{Chart: "container_bytes", Dimension: account + ".<container>"},
+	}
+}
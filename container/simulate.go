@@ -0,0 +1,55 @@
+// OpenIO netdata collectors
+// Copyright (C) 2019 OpenIO SAS
+//
+// This library is free software; you can redistribute it and/or
+// modify it under the terms of the GNU Lesser General Public
+// License as published by the Free Software Foundation; either
+// version 3.0 of the License, or (at your option) any later version.
+//
+// This library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package container
+
+import (
+	"fmt"
+	"oionetdata/netdata"
+	"oionetdata/util"
+)
+
+var simulations = make(map[string]*util.Simulation)
+
+/*
+Simulate - send synthetic account and container metrics with the charts and
+dimensions of Collect
+*/
+func Simulate(ns string, f bool, c chan netdata.Metric) {
+	sim, ok := simulations[ns]
+	if !ok {
+		sim = util.NewSimulation("container/" + ns)
+		simulations[ns] = sim
+	}
+	for a := 1; a <= 3; a++ {
+		acct := fmt.Sprintf("account%d", a)
+		if f {
+			bytes := sim.GaugeValue(acct+"bytes", 1e9, 1e12)
+			netdata.Update("account_bytes", util.AcctID(ns, acct), fmt.Sprint(uint64(bytes)), c)
+			netdata.Update("account_kilobytes", util.AcctID(ns, acct), fmt.Sprint(uint64(bytes/1000)), c)
+			netdata.Update("account_objects", util.AcctID(ns, acct), sim.Gauge(acct+"objects", 1e5, 1e7), c)
+		}
+		netdata.Update("container_count", util.AcctID(ns, acct), sim.Gauge(acct+"containers", 10, 50), c)
+		if !f {
+			for n := 1; n <= 3; n++ {
+				cont := fmt.Sprintf("container%d", n)
+				id := util.AcctID(ns, acct, cont)
+				netdata.Update("container_objects", id, sim.Gauge(id+"objects", 3e5, 1e6), c)
+				netdata.Update("container_bytes", id, sim.Gauge(id+"bytes", 1e9, 1e11), c)
+			}
+		}
+	}
+}
@@ -0,0 +1,311 @@
+// OpenIO netdata collectors
+// Copyright (C) 2019 OpenIO SAS
+//
+// This library is free software; you can redistribute it and/or
+// modify it under the terms of the GNU Lesser General Public
+// License as published by the Free Software Foundation; either
+// version 3.0 of the License, or (at your option) any later version.
+//
+// This library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package logger
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level -- severity of a message
+type Level int
+
+// Levels, in increasing severity
+const (
+	DebugLevel Level = iota
+	InfoLevel
+	WarnLevel
+	ErrorLevel
+)
+
+var levelNames = []string{"debug", "info", "warn", "error"}
+
+func (l Level) String() string {
+	if l < DebugLevel || l > ErrorLevel {
+		return fmt.Sprintf("level(%d)", int(l))
+	}
+	return levelNames[l]
+}
+
+// ParseLevel returns the level named s
+func ParseLevel(s string) (Level, error) {
+	for i, name := range levelNames {
+		if strings.EqualFold(s, name) {
+			return Level(i), nil
+		}
+	}
+	return InfoLevel, fmt.Errorf("unknown log level %q", s)
+}
+
+// DefaultWindow -- identical messages are written at most once per window
+const DefaultWindow = time.Minute
+
+// maxTracked -- number of distinct messages remembered before expired ones
+// are pruned
+const maxTracked = 1024
+
+// Config -- logger settings of a plugin
+type Config struct {
+	Level  string
+	JSON   bool
+	Window time.Duration
+}
+
+// Flags registers the logging flags on a plugin flag set
+func Flags(fs *flag.FlagSet) *Config {
+	conf := &Config{}
+	fs.StringVar(&conf.Level, "log-level", "info", "Minimum level of logged messages: debug, info, warn or error")
+	fs.BoolVar(&conf.JSON, "log-json", false, "Log messages as JSON objects")
+	fs.DurationVar(&conf.Window, "log-window", DefaultWindow, "Minimum delay before an identical message is logged again, 0 to log every message")
+	return conf
+}
+
+// Logger -- leveled logger that drops repeated messages
+type Logger struct {
+	sync.Mutex
+	out    io.Writer
+	level  Level
+	json   bool
+	window time.Duration
+	now    func() time.Time
+
+	seen  map[string]*seen
+	flush *time.Timer
+}
+
+type seen struct {
+	last       time.Time
+	suppressed int
+
+	// Message written with the count of suppressed repetitions once the
+	// window expires
+	level  Level
+	msg    string
+	fields []interface{}
+}
+
+// New returns a logger writing to out
+func New(out io.Writer, conf Config) (*Logger, error) {
+	level, err := ParseLevel(conf.Level)
+	if conf.Level == "" {
+		level, err = InfoLevel, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Logger{
+		out:    out,
+		level:  level,
+		json:   conf.JSON,
+		window: conf.Window,
+		now:    time.Now,
+		seen:   make(map[string]*seen),
+	}, nil
+}
+
+var std, _ = New(os.Stderr, Config{Window: DefaultWindow})
+
+// SetConfig replaces the shared logger, and routes the standard log package
+// through it; "ERROR", "WARN" and "DEBUG" prefixes set the level of such
+// messages, info otherwise
+func SetConfig(conf Config) error {
+	l, err := New(os.Stderr, conf)
+	if err != nil {
+		return err
+	}
+	std = l
+	log.SetFlags(0)
+	log.SetOutput(stdWriter{})
+	return nil
+}
+
+// stdWriter -- adapter for messages still written with the log package
+type stdWriter struct{}
+
+func (stdWriter) Write(p []byte) (int, error) {
+	msg := strings.TrimSpace(string(p))
+	level := InfoLevel
+	for _, prefix := range []string{"ERROR", "WARN", "DEBUG"} {
+		if strings.HasPrefix(msg, prefix) {
+			level, _ = ParseLevel(prefix)
+			msg = strings.TrimLeft(msg[len(prefix):], ": ")
+			break
+		}
+	}
+	std.Log(level, msg)
+	return len(p), nil
+}
+
+// Log writes msg with fields given as alternating keys and values, unless it
+// is below the level or was already written during the window. Errors are
+// never suppressed
+func (l *Logger) Log(level Level, msg string, fields ...interface{}) {
+	if level < l.level {
+		return
+	}
+	key := fmt.Sprint(level, msg, fields)
+
+	l.Lock()
+	defer l.Unlock()
+	now := l.now()
+	suppressed := 0
+	if l.window > 0 && level < ErrorLevel {
+		if s, ok := l.seen[key]; ok && now.Sub(s.last) < l.window {
+			s.suppressed++
+			l.armFlush()
+			return
+		} else if ok {
+			suppressed = s.suppressed
+		}
+		if len(l.seen) >= maxTracked {
+			l.prune(now)
+		}
+		l.seen[key] = &seen{last: now, level: level, msg: msg, fields: fields}
+	}
+	if l.window > 0 {
+		l.flushExpired(now)
+	}
+	if suppressed > 0 {
+		fields = append(fields, "suppressed", suppressed)
+	}
+	l.write(now, level, msg, fields)
+}
+
+func (l *Logger) write(now time.Time, level Level, msg string, fields []interface{}) {
+	if l.json {
+		l.writeJSON(now, level, msg, fields)
+	} else {
+		l.writeText(now, level, msg, fields)
+	}
+}
+
+// Flush writes the count of the repetitions suppressed during expired windows
+func (l *Logger) Flush() {
+	l.Lock()
+	defer l.Unlock()
+	l.flush = nil
+	l.flushExpired(l.now())
+	for _, s := range l.seen {
+		if s.suppressed > 0 {
+			l.armFlush()
+			break
+		}
+	}
+}
+
+// armFlush schedules a Flush, so that suppressed counts are reported even if
+// the message does not occur again
+func (l *Logger) armFlush() {
+	if l.flush == nil {
+		l.flush = time.AfterFunc(l.window, l.Flush)
+	}
+}
+
+func (l *Logger) flushExpired(now time.Time) {
+	for key, s := range l.seen {
+		if s.suppressed > 0 && now.Sub(s.last) >= l.window {
+			l.write(now, s.level, s.msg, append(append([]interface{}{}, s.fields...), "suppressed", s.suppressed))
+			delete(l.seen, key)
+		}
+	}
+}
+
+func (l *Logger) prune(now time.Time) {
+	l.flushExpired(now)
+	for key, s := range l.seen {
+		if now.Sub(s.last) >= l.window {
+			delete(l.seen, key)
+		}
+	}
+}
+
+func (l *Logger) writeText(now time.Time, level Level, msg string, fields []interface{}) {
+	var b strings.Builder
+	b.WriteString(now.Format("2006/01/02 15:04:05 "))
+	b.WriteString(strings.ToUpper(level.String()))
+	b.WriteString(" ")
+	b.WriteString(msg)
+	for i := 0; i < len(fields); i += 2 {
+		value := fmt.Sprint(field(fields, i+1))
+		if strings.ContainsAny(value, " \"=") || value == "" {
+			value = fmt.Sprintf("%q", value)
+		}
+		fmt.Fprintf(&b, " %v=%s", fields[i], value)
+	}
+	b.WriteString("\n")
+	io.WriteString(l.out, b.String())
+}
+
+func (l *Logger) writeJSON(now time.Time, level Level, msg string, fields []interface{}) {
+	entry := map[string]interface{}{
+		"time":  now.Format(time.RFC3339),
+		"level": level.String(),
+		"msg":   msg,
+	}
+	for i := 0; i < len(fields); i += 2 {
+		value := field(fields, i+1)
+		if err, ok := value.(error); ok {
+			value = err.Error()
+		}
+		entry[fmt.Sprint(fields[i])] = value
+	}
+	out, err := json.Marshal(entry)
+	if err != nil {
+		out, _ = json.Marshal(map[string]string{"level": "error", "msg": err.Error()})
+	}
+	l.out.Write(append(out, '\n'))
+}
+
+func field(fields []interface{}, i int) interface{} {
+	if i < len(fields) {
+		return fields[i]
+	}
+	return ""
+}
+
+// Debug logs a message at debug level with the shared logger
+func Debug(msg string, fields ...interface{}) {
+	std.Log(DebugLevel, msg, fields...)
+}
+
+// Info logs a message at info level with the shared logger
+func Info(msg string, fields ...interface{}) {
+	std.Log(InfoLevel, msg, fields...)
+}
+
+// Warn logs a message at warn level with the shared logger
+func Warn(msg string, fields ...interface{}) {
+	std.Log(WarnLevel, msg, fields...)
+}
+
+// Error logs a message at error level with the shared logger
+func Error(msg string, fields ...interface{}) {
+	std.Log(ErrorLevel, msg, fields...)
+}
+
+// Fatal logs a message at error level and exits
+func Fatal(msg string, fields ...interface{}) {
+	std.Log(ErrorLevel, msg, fields...)
+	os.Exit(1)
+}
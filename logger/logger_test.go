@@ -0,0 +1,111 @@
+// OpenIO netdata collectors
+// Copyright (C) 2019 OpenIO SAS
+//
+// This library is free software; you can redistribute it and/or
+// modify it under the terms of the GNU Lesser General Public
+// License as published by the Free Software Foundation; either
+// version 3.0 of the License, or (at your option) any later version.
+//
+// This library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := New(&buf, Config{Level: "warn", Window: time.Minute})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	now := time.Date(2019, 6, 1, 12, 0, 0, 0, time.UTC)
+	l.now = func() time.Time { return now }
+
+	l.Log(InfoLevel, "Started")
+	if buf.Len() != 0 {
+		t.Fatalf("message below level written: %q", buf.String())
+	}
+
+	for i := 0; i < 3; i++ {
+		l.Log(WarnLevel, "Failed to update", "target", "tcp://10.0.0.1:6011", "err", errors.New("connection refused"))
+	}
+	l.Log(WarnLevel, "Failed to update", "target", "tcp://10.0.0.2:6011")
+	expected := "2019/06/01 12:00:00 WARN Failed to update target=tcp://10.0.0.1:6011 err=\"connection refused\"\n" +
+		"2019/06/01 12:00:00 WARN Failed to update target=tcp://10.0.0.2:6011\n"
+	if buf.String() != expected {
+		t.Fatalf("unexpected output got\n%q\nexpected\n%q\n", buf.String(), expected)
+	}
+	buf.Reset()
+
+	now = now.Add(time.Minute)
+	l.Log(WarnLevel, "Failed to update", "target", "tcp://10.0.0.1:6011", "err", errors.New("connection refused"))
+	if !strings.HasSuffix(buf.String(), " suppressed=2\n") {
+		t.Fatalf("expected suppressed count, got %q", buf.String())
+	}
+
+	// Counts are flushed when the window expires, even if the message does
+	// not occur again
+	buf.Reset()
+	l.Log(WarnLevel, "Failed to update", "target", "tcp://10.0.0.1:6011", "err", errors.New("connection refused"))
+	now = now.Add(2 * time.Minute)
+	l.Flush()
+	expected = "2019/06/01 12:03:00 WARN Failed to update target=tcp://10.0.0.1:6011 err=\"connection refused\" suppressed=1\n"
+	if buf.String() != expected {
+		t.Fatalf("unexpected output got\n%q\nexpected\n%q\n", buf.String(), expected)
+	}
+
+	// Errors are never suppressed
+	buf.Reset()
+	l.Log(ErrorLevel, "Could not load config")
+	l.Log(ErrorLevel, "Could not load config")
+	if strings.Count(buf.String(), "Could not load config") != 2 {
+		t.Fatalf("expected both errors, got %q", buf.String())
+	}
+
+	if _, err := New(&buf, Config{Level: "verbose"}); err == nil {
+		t.Fatalf("expected error for unknown level")
+	}
+}
+
+func TestLoggerJSON(t *testing.T) {
+	var buf bytes.Buffer
+	l, _ := New(&buf, Config{JSON: true})
+	l.Log(ErrorLevel, "Failed to write output", "err", errors.New("broken pipe"), "count", 3)
+
+	entry := map[string]interface{}{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("invalid JSON %q: %v", buf.String(), err)
+	}
+	if entry["level"] != "error" || entry["msg"] != "Failed to write output" || entry["err"] != "broken pipe" || entry["count"] != float64(3) {
+		t.Fatalf("unexpected entry %v", entry)
+	}
+}
+
+func TestStdWriter(t *testing.T) {
+	var buf bytes.Buffer
+	prev := std
+	defer func() { std = prev }()
+	std, _ = New(&buf, Config{Level: "debug", JSON: true})
+
+	stdWriter{}.Write([]byte("ERROR: Redis plugin: missing targets\n"))
+	entry := map[string]interface{}{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("invalid JSON %q: %v", buf.String(), err)
+	}
+	if entry["level"] != "error" || entry["msg"] != "Redis plugin: missing targets" {
+		t.Fatalf("unexpected entry %v", entry)
+	}
+}
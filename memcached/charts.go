@@ -0,0 +1,133 @@
+// OpenIO netdata collectors
+// Copyright (C) 2019 OpenIO SAS
+//
+// This library is free software; you can redistribute it and/or
+// modify it under the terms of the GNU Lesser General Public
+// License as published by the Free Software Foundation; either
+// version 3.0 of the License, or (at your option) any later version.
+//
+// This library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package memcached
+
+import (
+	"fmt"
+	"oionetdata/netdata"
+	"oionetdata/util"
+)
+
+func init() {
+	netdata.Register("memcached", []netdata.Param{
+		{Name: "target", Description: util.TargetUsage, Required: true},
+	}, func(w netdata.Worker, params netdata.Params) error {
+		target, err := util.ParseTarget(params["target"])
+		if err != nil {
+			return err
+		}
+		return AddCharts(w, target)
+	})
+}
+
+// AddCharts adds a collector for target and its charts to a worker
+func AddCharts(w netdata.Worker, target util.Target) error {
+	if len(target.Extra) > 0 {
+		return fmt.Errorf("invalid address %s, must be IP:PORT", target.Addr)
+	}
+	var collector netdata.Collector = NewTargetCollector(target)
+	if util.Simulate {
+		collector = NewSimulatedCollector(target)
+	}
+	w.AddCollector(collector)
+	instance := "memcached." + target.Name
+	w.AddTarget(collector, instance, instance, "memcached")
+
+	uptimeChart := netdata.NewChart(instance, "uptime", "", "Uptime", "seconds", instance, "memcached.uptime.")
+	uptimeChart.AddDimension("uptime", "current", netdata.AbsoluteAlgorithm)
+	w.AddChart(uptimeChart, collector)
+
+	itemsChart := netdata.NewChart(instance, "items", "", "Items", "count", instance, "memcached.items.")
+	itemsChart.AddDimension("curr_items", "current", netdata.AbsoluteAlgorithm)
+	itemsChart.AddDimension("total_items", "total", netdata.IncrementalAlgorithm)
+	w.AddChart(itemsChart, collector)
+
+	memChart := netdata.NewChart(instance, "memory", "", "Memory", "bytes", instance, "memcached.memory")
+	memChart.AddDimension("bytes", "current", netdata.AbsoluteAlgorithm)
+	memChart.AddDimension("limit_maxbytes", "max", netdata.AbsoluteAlgorithm)
+	w.AddChart(memChart, collector)
+
+	connectionsChart := netdata.NewChart(instance, "connections", "", "Connections", "count", instance, "memcached.connections")
+	connectionsChart.AddDimension("max_connections", "max", netdata.AbsoluteAlgorithm)
+	connectionsChart.AddDimension("curr_connections", "current", netdata.AbsoluteAlgorithm)
+	connectionsChart.AddDimension("total_connections", "total", netdata.IncrementalAlgorithm)
+	connectionsChart.AddDimension("rejected_connections", "rejected", netdata.IncrementalAlgorithm)
+	connectionsChart.AddDimension("accepting_conns", "accepting", netdata.AbsoluteAlgorithm)
+	connectionsChart.AddDimension("listen_disabled_num", "disabled", netdata.AbsoluteAlgorithm)
+	connectionsChart.AddDimension("conn_yields", "yield", netdata.AbsoluteAlgorithm)
+	w.AddChart(connectionsChart, collector)
+
+	reqsChart := netdata.NewChart(instance, "requests", "", "Requests", "requests", instance, "memcached.requests")
+	reqsChart.AddDimension("cmd_get", "get", netdata.IncrementalAlgorithm)
+	reqsChart.AddDimension("cmd_set", "set", netdata.IncrementalAlgorithm)
+	reqsChart.AddDimension("cmd_flush", "flush", netdata.IncrementalAlgorithm)
+	reqsChart.AddDimension("cmd_touch", "touch", netdata.IncrementalAlgorithm)
+	w.AddChart(reqsChart, collector)
+
+	getsChart := netdata.NewChart(instance, "get_requests", "", "Get requests", "requests", instance, "memcached.get_requests")
+	getsChart.AddDimension("get_hits", "hits", netdata.IncrementalAlgorithm)
+	getsChart.AddDimension("get_misses", "misses", netdata.IncrementalAlgorithm)
+	getsChart.AddDimension("get_expired", "expired", netdata.IncrementalAlgorithm)
+	getsChart.AddDimension("get_flushed", "flushed", netdata.IncrementalAlgorithm)
+	w.AddChart(getsChart, collector)
+
+	deletesChart := netdata.NewChart(instance, "delete_requests", "", "Delete requests", "requests", instance, "memcached.delete_requests")
+	deletesChart.AddDimension("delete_hits", "hits", netdata.IncrementalAlgorithm)
+	deletesChart.AddDimension("delete_misses", "misses", netdata.IncrementalAlgorithm)
+	w.AddChart(deletesChart, collector)
+
+	incrsChart := netdata.NewChart(instance, "incr_requests", "", "Incr requests", "requests", instance, "memcached.incr_requests")
+	incrsChart.AddDimension("incr_hits", "hits", netdata.IncrementalAlgorithm)
+	incrsChart.AddDimension("incr_misses", "misses", netdata.IncrementalAlgorithm)
+	w.AddChart(incrsChart, collector)
+
+	decrsChart := netdata.NewChart(instance, "decr_requests", "", "Decr requests", "requests", instance, "memcached.decr_requests")
+	decrsChart.AddDimension("decr_hits", "hits", netdata.IncrementalAlgorithm)
+	decrsChart.AddDimension("decr_misses", "misses", netdata.IncrementalAlgorithm)
+	w.AddChart(decrsChart, collector)
+
+	cassChart := netdata.NewChart(instance, "cas_requests", "", "CAS requests", "requests", instance, "memcached.cas_requests")
+	cassChart.AddDimension("cas_hits", "hits", netdata.IncrementalAlgorithm)
+	cassChart.AddDimension("cas_misses", "misses", netdata.IncrementalAlgorithm)
+	cassChart.AddDimension("cas_bandval", "badval", netdata.IncrementalAlgorithm)
+	w.AddChart(cassChart, collector)
+
+	touchsChart := netdata.NewChart(instance, "touch_requests", "", "Touch requests", "requests", instance, "memcached.touch_requests")
+	touchsChart.AddDimension("touch_hits", "hits", netdata.IncrementalAlgorithm)
+	touchsChart.AddDimension("touch_misses", "misses", netdata.IncrementalAlgorithm)
+	w.AddChart(touchsChart, collector)
+
+	authsChart := netdata.NewChart(instance, "auth_requests", "", "Auth requests", "requests", instance, "memcached.auth_requests")
+	authsChart.AddDimension("auth_cmds", "total", netdata.IncrementalAlgorithm)
+	authsChart.AddDimension("auth_errors", "errors", netdata.IncrementalAlgorithm)
+	w.AddChart(authsChart, collector)
+
+	netChart := netdata.NewChart(instance, "net", "", "Network", "bytes", instance, "memcached.net")
+	netChart.AddDimension("bytes_read", "in", netdata.IncrementalAlgorithm)
+	netChart.AddDimension("bytes_written", "out", netdata.IncrementalAlgorithm)
+	w.AddChart(netChart, collector)
+
+	lruChart := netdata.NewChart(instance, "lru", "", "LRU", "items", instance, "memcached.lru")
+	lruChart.AddDimension("expired_unfetched", "expired_unfetched", netdata.IncrementalAlgorithm)
+	lruChart.AddDimension("evicted_unfetched", "evicted_unfetched", netdata.IncrementalAlgorithm)
+	lruChart.AddDimension("evicted_active", "evicted_active", netdata.IncrementalAlgorithm)
+	lruChart.AddDimension("moves_to_cold", "moves_to_cold", netdata.IncrementalAlgorithm)
+	lruChart.AddDimension("moves_to_warm", "moves_to_warm", netdata.IncrementalAlgorithm)
+	lruChart.AddDimension("moves_within_lru", "moves_within_lru", netdata.IncrementalAlgorithm)
+	w.AddChart(lruChart, collector)
+	return nil
+}
@@ -18,26 +18,38 @@ package memcached
 
 import (
 	"bufio"
-	"net"
+	"oionetdata/netdata"
+	"oionetdata/util"
 	"strings"
+	"time"
 )
 
 type collector struct {
-	addr string
+	target      util.Target
+	connectTime time.Duration
 }
 
 func NewCollector(addr string) *collector {
+	return NewTargetCollector(util.Target{Network: "tcp", Addr: addr, Name: addr})
+}
+
+func NewTargetCollector(target util.Target) *collector {
 	return &collector{
-		addr: addr,
+		target: target,
 	}
 }
 
+func (c *collector) ConnectTime() time.Duration {
+	return c.connectTime
+}
+
 func (c *collector) Collect() (map[string]string, error) {
-	conn, err := net.Dial("tcp", c.addr)
+	conn, connectTime, err := c.target.Dial(util.DefaultDialTimeout)
 	if err != nil {
 		return nil, err
 	}
 	defer conn.Close()
+	c.connectTime = connectTime
 
 	_, err = conn.Write([]byte("stats\r\n"))
 	if err != nil {
@@ -46,6 +58,8 @@ func (c *collector) Collect() (map[string]string, error) {
 
 	data := map[string]string{}
 
+	// Lines that are neither STAT nor END, reported if no stat could be read
+	var malformed []string
 	scanner := bufio.NewScanner(conn)
 	for scanner.Scan() {
 		line := scanner.Text()
@@ -54,16 +68,21 @@ func (c *collector) Collect() (map[string]string, error) {
 			conn.Close()
 			break
 		}
-		if len(kv) != 3 {
+		if len(kv) != 3 || kv[0] != "STAT" {
+			malformed = append(malformed, line)
 			continue
 		}
-		if kv[0] == "STAT" {
-			data[kv[1]] = kv[2]
-		}
+		data[kv[1]] = kv[2]
 	}
 
 	if err := scanner.Err(); err != nil {
 		return nil, err
 	}
+	if len(data) == 0 && len(malformed) > 0 {
+		return nil, netdata.ParseErrorf("could not parse response from %s: %q", c.target, malformed[0])
+	}
+	if len(data) == 0 {
+		return nil, netdata.ProtocolErrorf("no STAT lines in response from %s", c.target)
+	}
 	return data, nil
 }
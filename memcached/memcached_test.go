@@ -22,8 +22,12 @@ import (
 	"io/ioutil"
 	"log"
 	"net"
+	"os"
 	"reflect"
 	"testing"
+
+	"oionetdata/netdata"
+	"oionetdata/util"
 )
 
 type testServer struct {
@@ -173,3 +177,38 @@ func TestMemcachedCollector(t *testing.T) {
 		t.Fatalf("expected error")
 	}
 }
+
+func TestSimulatedCollector(t *testing.T) {
+	collector := NewSimulatedCollector(util.Target{Network: "tcp", Addr: ":11211", Name: "simulated"})
+	data, err := collector.Collect()
+	if err != nil {
+		t.Fatalf("unexpected Collect error: %v", err)
+	}
+	for key := range data {
+		if _, ok := expected[key]; !ok {
+			t.Fatalf("unexpected simulated key %s", key)
+		}
+	}
+}
+
+func TestMemcachedParseError(t *testing.T) {
+	spec, err := ioutil.TempFile("", "memcached")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(spec.Name())
+	spec.WriteString("STATS pid 1\r\nEND\r\n")
+	spec.Close()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen error: %v", err)
+	}
+	defer l.Close()
+	go newTestServer(spec.Name()).Run(l)
+
+	_, err = NewCollector(l.Addr().String()).Collect()
+	if class := netdata.ErrorClass(err); class != netdata.ErrorParse {
+		t.Fatalf("expected a parse error, got %v (%s)", err, class)
+	}
+}
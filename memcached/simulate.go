@@ -0,0 +1,66 @@
+// OpenIO netdata collectors
+// Copyright (C) 2019 OpenIO SAS
+//
+// This library is free software; you can redistribute it and/or
+// modify it under the terms of the GNU Lesser General Public
+// License as published by the Free Software Foundation; either
+// version 3.0 of the License, or (at your option) any later version.
+//
+// This library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package memcached
+
+import (
+	"oionetdata/util"
+	"time"
+)
+
+type simulator struct {
+	sim *util.Simulation
+}
+
+// NewSimulatedCollector returns a collector producing synthetic stats for
+// target, without connecting to it
+func NewSimulatedCollector(target util.Target) *simulator {
+	return &simulator{
+		sim: util.NewSimulation("memcached/" + target.String()),
+	}
+}
+
+func (s *simulator) ConnectTime() time.Duration {
+	return time.Duration(s.sim.GaugeValue("connect_time", 100, 2000)) * time.Microsecond
+}
+
+// simulatedCounters -- approximate increase of each counter per collection
+var simulatedCounters = map[string]float64{
+	"uptime": 10, "total_items": 50, "total_connections": 2, "rejected_connections": 0.01, "conn_yields": 0.1,
+	"cmd_get": 1000, "cmd_set": 50, "cmd_flush": 0.01, "cmd_touch": 5,
+	"get_hits": 900, "get_misses": 100, "get_expired": 5, "get_flushed": 0.1,
+	"delete_hits": 10, "delete_misses": 1, "incr_hits": 20, "incr_misses": 1, "decr_hits": 5, "decr_misses": 1,
+	"cas_hits": 5, "cas_misses": 1, "cas_badval": 0.5, "touch_hits": 4, "touch_misses": 1,
+	"auth_cmds": 0, "auth_errors": 0, "bytes_read": 5e4, "bytes_written": 5e5,
+	"expired_unfetched": 1, "evicted_unfetched": 1, "evicted_active": 0.5,
+	"moves_to_cold": 20, "moves_to_warm": 5, "moves_within_lru": 2,
+}
+
+func (s *simulator) Collect() (map[string]string, error) {
+	data := map[string]string{
+		"curr_items":          s.sim.Gauge("curr_items", 1e4, 5e4),
+		"bytes":               s.sim.Gauge("bytes", 1e7, 6e7),
+		"limit_maxbytes":      "67108864",
+		"max_connections":     "1024",
+		"curr_connections":    s.sim.Gauge("curr_connections", 10, 100),
+		"accepting_conns":     "1",
+		"listen_disabled_num": "0",
+	}
+	for key, rate := range simulatedCounters {
+		data[key] = s.sim.Counter(key, rate)
+	}
+	return data, nil
+}
@@ -41,6 +41,11 @@ type Chart struct {
 	dimensions      map[string]Dimension
 	dimensionsIndex []string
 
+	// Dimensions admitted under MaxDimensions, and those declared by the last
+	// chart definition, nil when not limited
+	admission *admission
+	declared  map[string]bool
+
 	refresh bool
 }
 
@@ -90,13 +95,57 @@ func (c *Chart) create(out Writer) {
 	chartCreate := fmt.Sprintf("CHART %s.%s '%s' '%s' '%s' '%s' '%s'", c.Type, c.ID, c.Name, c.Title, c.Units, c.Family, c.Category)
 	dimensionsCreate := []string{}
 	for _, dimID := range c.dimensionsIndex {
+		if c.admission != nil && !c.admission.dims[dimID] && dimID != OtherDimension {
+			continue
+		}
 		dim := c.dimensions[dimID]
 		dimensionsCreate = append(dimensionsCreate, dim.create())
+		if c.declared != nil {
+			c.declared[dimID] = true
+		}
 	}
 	out.Printf("%v\n%v\n", chartCreate, strings.Join(dimensionsCreate, "\n"))
 	c.refresh = false
 }
 
+// limitDimensions keeps the values of the dimensions admitted under
+// MaxDimensions and sums the others in the other dimension. It returns the
+// values to send and the number of dimensions summed in other. The chart is
+// defined again when a dimension it did not declare is admitted
+func (c *Chart) limitDimensions(data map[string]string) (map[string]string, int) {
+	var dims []string
+	for _, dimID := range c.dimensionsIndex {
+		if _, ok := data[dimID]; ok && dimID != OtherDimension {
+			dims = append(dims, dimID)
+		}
+	}
+	if c.admission == nil {
+		if len(c.dimensionsIndex) <= limits.MaxDimensions {
+			return data, 0
+		}
+		c.admission = newAdmission()
+		c.declared = make(map[string]bool)
+	}
+	kept, other := c.admission.admit(dims, data, limits)
+	values := make(map[string]string)
+	for _, dimID := range kept {
+		values[dimID] = data[dimID]
+		if !c.declared[dimID] {
+			c.refresh = true
+		}
+	}
+	if len(other) > 0 {
+		if _, ok := c.dimensions[OtherDimension]; !ok {
+			c.AddDimension(OtherDimension, OtherDimension, c.dimensions[other[0]].algorithm)
+		}
+		if !c.declared[OtherDimension] {
+			c.refresh = true
+		}
+		values[OtherDimension] = sumValues(other, data)
+	}
+	return values, len(other)
+}
+
 func (c *Chart) Update(data map[string]string, interval time.Duration, out Writer) bool {
 	var updatedDimensions []string
 	for _, dimID := range c.dimensionsIndex {
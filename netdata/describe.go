@@ -0,0 +1,183 @@
+// OpenIO netdata collectors
+// Copyright (C) 2019 OpenIO SAS
+//
+// This library is free software; you can redistribute it and/or
+// modify it under the terms of the GNU Lesser General Public
+// License as published by the Free Software Foundation; either
+// version 3.0 of the License, or (at your option) any later version.
+//
+// This library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package netdata
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Description formats
+const (
+	FormatJSON     = "json"
+	FormatMarkdown = "markdown"
+)
+
+// ChartInfo -- description of a chart emitted by a plugin. Charts and
+// dimensions only known at runtime are described by patterns, with
+// placeholders between angle brackets, e.g. <service_id>
+type ChartInfo struct {
+	Type       string          `json:"type"`
+	ID         string          `json:"id"`
+	Name       string          `json:"name,omitempty"`
+	Title      string          `json:"title"`
+	Units      string          `json:"units"`
+	Family     string          `json:"family"`
+	Context    string          `json:"context"`
+	Dynamic    bool            `json:"dynamic"`
+	Dimensions []DimensionInfo `json:"dimensions"`
+}
+
+// DimensionInfo -- description of a dimension of a chart
+type DimensionInfo struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Algorithm Algorithm `json:"algorithm"`
+}
+
+// DescribeFlag registers the --describe flag on a plugin flag set
+func DescribeFlag(fs *flag.FlagSet) *string {
+	return fs.String("describe", "", "Print the charts the plugin would emit for this configuration, as json or markdown, and exit")
+}
+
+// Info returns the description of a chart
+func (c *Chart) Info() ChartInfo {
+	info := ChartInfo{
+		Type:    c.Type,
+		ID:      c.ID,
+		Name:    c.Name,
+		Title:   c.Title,
+		Units:   c.Units,
+		Family:  c.Family,
+		Context: c.Category,
+	}
+	for _, dimID := range c.dimensionsIndex {
+		dim := c.dimensions[dimID]
+		info.Dimensions = append(info.Dimensions, DimensionInfo{ID: dim.id, Name: dim.name, Algorithm: dim.algorithm})
+	}
+	info.Dynamic = isPattern(info.Type + info.ID)
+	for _, dim := range info.Dimensions {
+		info.Dynamic = info.Dynamic || isPattern(dim.ID)
+	}
+	return info
+}
+
+// Describe returns the charts registered on the worker, in registration order
+func (w *worker) Describe() []ChartInfo {
+	var charts []ChartInfo
+	seen := make(map[string]bool)
+	for _, collector := range w.collectors {
+		for _, chartID := range w.chartsIndex[collector] {
+			if seen[chartID] {
+				continue
+			}
+			seen[chartID] = true
+			charts = append(charts, w.charts[chartID].Info())
+		}
+	}
+	if limits.enabled() {
+		charts = append(charts, newDroppedChart().Info())
+	}
+	return charts
+}
+
+// LegacyPattern -- chart fed through Update, with the pattern of its
+// dimensions
+type LegacyPattern struct {
+	Chart     string
+	Dimension string
+}
+
+// DescribeLegacy returns the description of the charts fed through Update,
+// skipping those dropped by the relabel rules. Patterns of the same chart are
+// merged
+func DescribeLegacy(patterns []LegacyPattern) []ChartInfo {
+	var index []string
+	legacy := make(map[string]*Chart)
+	for _, p := range patterns {
+		l := legacyLabels(fmt.Sprintf("%s.%s", Prefix, strings.Replace(p.Chart, ".", "_", -1)))
+		name, ok := l.dimension(p.Dimension)
+		if !ok {
+			continue
+		}
+		c, ok := legacy[l.chart]
+		if !ok {
+			title := strings.ToUpper(strings.Join(strings.Split(l.chart, "_"), " "))
+			c = NewChart(l.typ, strings.TrimPrefix(l.chart, l.typ+"."), l.desc, title, "", l.family, "")
+			legacy[l.chart] = c
+			index = append(index, l.chart)
+		}
+		c.AddDimension(p.Dimension, name, AbsoluteAlgorithm)
+	}
+	var charts []ChartInfo
+	for _, chart := range index {
+		charts = append(charts, legacy[chart].Info())
+	}
+	if limits.enabled() {
+		charts = append(charts, newDroppedChart().Info())
+	}
+	return charts
+}
+
+func isPattern(s string) bool {
+	return strings.Contains(s, "<")
+}
+
+// WriteDescription writes a chart catalogue in the given format
+func WriteDescription(out io.Writer, format string, charts []ChartInfo) error {
+	switch format {
+	case FormatJSON:
+		if charts == nil {
+			charts = []ChartInfo{}
+		}
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		return enc.Encode(charts)
+	case FormatMarkdown:
+		return writeMarkdown(out, charts)
+	}
+	return fmt.Errorf("unknown description format %q, expected %s or %s", format, FormatJSON, FormatMarkdown)
+}
+
+func writeMarkdown(out io.Writer, charts []ChartInfo) error {
+	var b strings.Builder
+	b.WriteString("| Chart | Title | Units | Family | Context | Dimensions |\n")
+	b.WriteString("|---|---|---|---|---|---|\n")
+	for _, c := range charts {
+		var dims []string
+		for _, d := range c.Dimensions {
+			dim := fmt.Sprintf("`%s`", d.ID)
+			if d.Name != "" && d.Name != d.ID {
+				dim += " (" + d.Name + ")"
+			}
+			dims = append(dims, dim+" "+string(d.Algorithm))
+		}
+		fmt.Fprintf(&b, "| `%s.%s` | %s | %s | %s | %s | %s |\n",
+			c.Type, c.ID, cell(c.Title), cell(c.Units), cell(c.Family), cell(c.Context), strings.Join(dims, "<br>"))
+	}
+	_, err := io.WriteString(out, b.String())
+	return err
+}
+
+// cell escapes a value for a markdown table cell
+func cell(s string) string {
+	return strings.NewReplacer("|", "\\|", "<", "&lt;", ">", "&gt;").Replace(s)
+}
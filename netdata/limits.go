@@ -0,0 +1,177 @@
+// OpenIO netdata collectors
+// Copyright (C) 2019 OpenIO SAS
+//
+// This library is free software; you can redistribute it and/or
+// modify it under the terms of the GNU Lesser General Public
+// License as published by the Free Software Foundation; either
+// version 3.0 of the License, or (at your option) any later version.
+//
+// This library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package netdata
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// OtherDimension -- aggregates the values of dimensions over the limit
+const OtherDimension = "other"
+
+// Limits -- cardinality guard for charts and dimensions created at runtime,
+// 0 means unlimited. Charts are admitted first-come. Dimensions are ranked by
+// value every RerankCycles cycles, those out of the top are evicted to the
+// "other" aggregate. In between, admitted dimensions keep their slot and new
+// ones compete by value for the free slots
+type Limits struct {
+	// Total number of charts of the plugin
+	MaxCharts int
+	// Number of dimensions per chart, including the "other" aggregate
+	MaxDimensions int
+	// Cycles between two rankings of the dimensions, defaultRerankCycles if 0
+	RerankCycles int
+}
+
+const defaultRerankCycles = 10
+
+var limits = Limits{}
+
+var droppedChart *Chart
+
+func (l Limits) enabled() bool {
+	return l.MaxCharts > 0 || l.MaxDimensions > 0
+}
+
+// LimitFlags registers the cardinality guard flags on a plugin flag set
+func LimitFlags(fs *flag.FlagSet) *Limits {
+	l := &Limits{}
+	fs.IntVar(&l.MaxCharts, "max-charts", 0, "Maximum number of charts, 0 for unlimited")
+	fs.IntVar(&l.MaxDimensions, "max-dimensions", 0, "Maximum number of dimensions per chart, 0 for unlimited")
+	fs.IntVar(&l.RerankCycles, "rerank-cycles", defaultRerankCycles, "Cycles between two rankings by value of the dimensions of a chart over --max-dimensions")
+	return l
+}
+
+// SetLimits sets the cardinality guard of the plugin
+func SetLimits(l Limits) {
+	limits = l
+}
+
+func (l Limits) rerankCycles() int {
+	if l.RerankCycles > 0 {
+		return l.RerankCycles
+	}
+	return defaultRerankCycles
+}
+
+// admission -- dimensions of a chart admitted under MaxDimensions
+type admission struct {
+	dims   map[string]bool
+	other  bool
+	cycles int
+}
+
+func newAdmission() *admission {
+	return &admission{dims: make(map[string]bool)}
+}
+
+// admit splits the dimensions with a value in the cycle between those sent and
+// those summed in the other aggregate. On ranking cycles, all of them compete by
+// value. Otherwise admitted dimensions keep their slot, unless one must make
+// room for the aggregate, and new ones take the free slots by value
+func (a *admission) admit(dims []string, values map[string]string, l Limits) ([]string, []string) {
+	if a.cycles%l.rerankCycles() == 0 {
+		a.dims = make(map[string]bool)
+		a.other = false
+	}
+	a.cycles++
+	var known, added []string
+	for _, dim := range dims {
+		if a.dims[dim] {
+			known = append(known, dim)
+		} else {
+			added = append(added, dim)
+		}
+	}
+	// Once needed, the aggregate keeps its slot until the next ranking
+	capacity := l.MaxDimensions
+	if a.other || len(a.dims)+len(added) > capacity {
+		a.other = true
+		capacity--
+	}
+	known, evicted := topDimensions(known, values, capacity)
+	for _, dim := range evicted {
+		delete(a.dims, dim)
+	}
+	added, other := topDimensions(added, values, capacity-len(a.dims))
+	for _, dim := range added {
+		a.dims[dim] = true
+	}
+	return append(known, added...), append(evicted, other...)
+}
+
+// topDimensions keeps the budget dimensions with the highest values and
+// returns the others separately
+func topDimensions(dims []string, values map[string]string, budget int) ([]string, []string) {
+	if budget < 0 {
+		budget = 0
+	}
+	if len(dims) <= budget {
+		return dims, nil
+	}
+	sorted := append([]string{}, dims...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return parseValue(values[sorted[i]]) > parseValue(values[sorted[j]])
+	})
+	return sorted[:budget], sorted[budget:]
+}
+
+func sumValues(dims []string, values map[string]string) string {
+	var sum float64
+	for _, dim := range dims {
+		sum += parseValue(values[dim])
+	}
+	return strconv.FormatFloat(sum, 'f', -1, 64)
+}
+
+func parseValue(value string) float64 {
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0
+	}
+	return v
+}
+
+func pluginName() string {
+	name := strings.TrimSuffix(filepath.Base(os.Args[0]), ".plugin")
+	return strings.Replace(name, ".", "_", -1)
+}
+
+func newDroppedChart() *Chart {
+	chart := NewChart("netdata", pluginName()+"_dropped_series", "", "Series dropped by cardinality limits", "series", "plugins", "netdata.plugin_dropped_series")
+	chart.AddDimension("charts", "charts", AbsoluteAlgorithm)
+	chart.AddDimension("dimensions", "dimensions", AbsoluteAlgorithm)
+	return chart
+}
+
+// reportDropped updates the self-monitoring chart with the number of charts
+// and dimensions dropped during the last cycle, i.e. of series that had a value
+// but were not sent, on both the worker and Flush paths
+func reportDropped(out Writer, charts int, dims int) {
+	if droppedChart == nil {
+		droppedChart = newDroppedChart()
+	}
+	droppedChart.Update(map[string]string{
+		"charts":     strconv.Itoa(charts),
+		"dimensions": strconv.Itoa(dims),
+	}, 0, out)
+}
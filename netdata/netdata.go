@@ -18,18 +18,21 @@ package netdata
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 	"sync"
 )
 
 type index struct {
 	sync.RWMutex
-	charts map[string]map[string]bool
+	charts     map[string]map[string]bool
+	admissions map[string]*admission
 }
 
 func makeIndex() *index {
 	return &index{
-		charts: make(map[string]map[string]bool),
+		charts:     make(map[string]map[string]bool),
+		admissions: make(map[string]*admission),
 	}
 }
 
@@ -47,6 +50,12 @@ func (i *index) dimExists(chart string, dim string) bool {
 	return e
 }
 
+func (i *index) chartCount() int {
+	i.RLock()
+	defer i.RUnlock()
+	return len(i.charts)
+}
+
 func (i *index) addChart(chart string) {
 	i.Lock()
 	defer i.Unlock()
@@ -59,6 +68,15 @@ func (i *index) addDim(chart string, dim string) {
 	i.charts[chart][dim] = true
 }
 
+func (i *index) admission(chart string) *admission {
+	i.Lock()
+	defer i.Unlock()
+	if _, ok := i.admissions[chart]; !ok {
+		i.admissions[chart] = newAdmission()
+	}
+	return i.admissions[chart]
+}
+
 var chartIndex = makeIndex()
 
 // Prefix -- prefix to use for metrics
@@ -71,35 +89,198 @@ type Metric struct {
 	Chart string
 	Dim   string
 	Value string
+	// Label metrics set the label Dim of the chart to Value
+	Label bool
 }
 
 /*
 Update - queue a new metric value on a chart
 */
 func Update(chart string, dim string, value string, c chan Metric) {
-	chart = fmt.Sprintf("%s.%s", Prefix, strings.Replace(chart, ".", "_", -1))
-	chartTitle := strings.ToUpper(strings.Join(strings.Split(chart, "_"), " "))
-	if !chartIndex.chartExists(chart) {
-		createChart(chart, "", chartTitle, "", "")
-		chartIndex.addChart(chart)
-	}
-	if !chartIndex.dimExists(chart, dim) {
-		createChart(chart, "", chartTitle, "", dim)
-		chartIndex.addDim(chart, dim)
+	c <- Metric{
+		Chart: fmt.Sprintf("%s.%s", Prefix, strings.Replace(chart, ".", "_", -1)),
+		Dim:   dim,
+		Value: value,
 	}
+}
 
+/*
+Label - queue a label of a chart, sent with the chart definition whenever it
+changes
+*/
+func Label(chart string, name string, value string, c chan Metric) {
 	c <- Metric{
-		Chart: chart,
-		Dim:   dim,
+		Chart: fmt.Sprintf("%s.%s", Prefix, strings.Replace(chart, ".", "_", -1)),
+		Dim:   name,
 		Value: value,
+		Label: true,
 	}
 }
 
-func createChart(chart string, desc string, title string, units string, dim string) {
+/*
+Flush - write all metrics queued so far as a single update, creating missing
+charts and dimensions first within the configured limits. Metrics queued while
+flushing are left on the channel for the next cycle.
+*/
+func Flush(c chan Metric, out Writer) error {
+	var charts []string
+	values := make(map[string]map[string]string)
+	dims := make(map[string][]string)
+
+	for pending := len(c); pending > 0; pending-- {
+		m := <-c
+		if m.Label {
+			if l := legacyLabels(m.Chart); !l.dropped {
+				l.setLabel(m.Dim, m.Value)
+			}
+			continue
+		}
+		if _, ok := legacyLabels(m.Chart).dimension(m.Dim); !ok {
+			continue
+		}
+		if _, ok := values[m.Chart]; !ok {
+			charts = append(charts, m.Chart)
+			values[m.Chart] = make(map[string]string)
+		}
+		if _, ok := values[m.Chart][m.Dim]; !ok {
+			dims[m.Chart] = append(dims[m.Chart], m.Dim)
+		}
+		values[m.Chart][m.Dim] = m.Value
+	}
+
+	droppedCharts, droppedDims := 0, 0
+	for _, chart := range charts {
+		l := legacyLabels(chart)
+		chartTitle := strings.ToUpper(strings.Join(strings.Split(l.chart, "_"), " "))
+		created := false
+		if !chartIndex.chartExists(chart) {
+			if limits.MaxCharts > 0 && chartIndex.chartCount() >= limits.MaxCharts {
+				droppedCharts++
+				droppedDims += len(dims[chart])
+				continue
+			}
+			createChart(l.chart, l.desc, chartTitle, "", l.family, "", "", out)
+			chartIndex.addChart(chart)
+			created = true
+		}
+		if l.labelsChanged || (created && len(l.labels) > 0) {
+			if !created {
+				createChart(l.chart, l.desc, chartTitle, "", l.family, "", "", out)
+			}
+			writeLabels(l.labels, out)
+			l.labelsChanged = false
+		}
+
+		sent := dims[chart]
+		if limits.MaxDimensions > 0 {
+			var other []string
+			sent, other = chartIndex.admission(chart).admit(sent, values[chart], limits)
+			if len(other) > 0 {
+				droppedDims += len(other)
+				values[chart][OtherDimension] = sumValues(other, values[chart])
+				sent = append(sent, OtherDimension)
+			}
+		}
+		for _, dim := range sent {
+			if chartIndex.dimExists(chart, dim) {
+				continue
+			}
+			name, ok := l.dimension(dim)
+			if !ok {
+				name = dim
+			}
+			createChart(l.chart, l.desc, chartTitle, "", l.family, dim, name, out)
+			chartIndex.addDim(chart, dim)
+		}
+
+		sets := ""
+		for _, dim := range sent {
+			sets += fmt.Sprintf("SET %s %s\n", dim, values[chart][dim])
+		}
+		out.Printf("BEGIN %s\n%sEND\n", l.chart, sets)
+	}
+
+	if limits.enabled() {
+		reportDropped(out, droppedCharts, droppedDims)
+	}
+	return out.Flush()
+}
+
+func createChart(chart string, desc string, title string, units string, family string, dim string, dimName string, out Writer) {
 	if dim != "" {
-		dim = fmt.Sprintf("DIMENSION %s '%s' absolute\n", dim, dim)
+		dim = fmt.Sprintf("DIMENSION %s '%s' absolute\n", dim, dimName)
+	}
+	out.Printf("CHART %s '%s' '%s' '%s' '%s'\n%s", chart, desc, title, units, family, dim)
+}
+
+// legacyChart -- output attributes of a chart fed through Update, once the
+// relabel rules are applied
+type legacyChart struct {
+	typ, id string
+	chart   string
+	desc    string
+	family  string
+	dropped bool
+	// Dimension names, empty for dropped dimensions
+	names map[string]string
+	// Labels sent with the chart definition, changed ones are sent again on
+	// the next update of the chart
+	labels        map[string]string
+	labelsChanged bool
+}
+
+var legacyCharts = make(map[string]*legacyChart)
+
+func legacyLabels(chart string) *legacyChart {
+	if l, ok := legacyCharts[chart]; ok {
+		return l
+	}
+	typ, id := chart, ""
+	if i := strings.Index(chart, "."); i >= 0 {
+		typ, id = chart[:i], chart[i+1:]
+	}
+	labels := labels{id: id, family: getFamily(chart)}
+	l := &legacyChart{typ: typ, id: id, names: make(map[string]string), labels: make(map[string]string)}
+	l.dropped = !relabel.chart(typ, &labels)
+	l.chart = typ + "." + labels.id
+	l.desc, l.family = labels.name, labels.family
+	legacyCharts[chart] = l
+	return l
+}
+
+// dimension returns the name of a dimension, and false if it is dropped
+func (l *legacyChart) dimension(dim string) (string, bool) {
+	if l.dropped {
+		return "", false
+	}
+	if name, ok := l.names[dim]; ok {
+		return name, name != ""
+	}
+	name := dim
+	if !relabel.dimension(l.typ, l.id, dim, &name) {
+		name = ""
+	}
+	l.names[dim] = name
+	return name, name != ""
+}
+
+func (l *legacyChart) setLabel(name string, value string) {
+	if current, ok := l.labels[name]; !ok || current != value {
+		l.labels[name] = value
+		l.labelsChanged = true
+	}
+}
+
+func writeLabels(labels map[string]string, out Writer) {
+	var names []string
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		out.Printf("CLABEL '%s' '%s' 1\n", name, strings.Replace(labels[name], "'", "", -1))
 	}
-	fmt.Printf("CHART %s '%s' '%s' '%s' '%s'\n%s", chart, desc, title, units, getFamily(chart), dim)
+	out.Printf("CLABEL_COMMIT\n")
 }
 
 func getFamily(chart string) string {
@@ -107,12 +288,21 @@ func getFamily(chart string) string {
 		"req":       "Request",
 		"rep":       "Response",
 		"score":     "Score",
+		"stat":      "Score",
 		"byte":      "Capacity",
 		"inodes":    "Inodes",
+		"disk":      "Disk",
 		"cnx":       "Connections",
 		"zk":        "Zookeeper",
 		"container": "Container",
 		"account":   "Account",
+		"cluster":   "Cluster",
+		"rawx":      "Rawx",
+		"rdir":      "Rdir",
+		"oioproxy":  "Proxy",
+		"proxy":     "Proxy",
+		"sqlx":      "Sqlx",
+		"service":   "Services",
 	}
 
 	chart = strings.Split(chart, ".")[1]
@@ -0,0 +1,272 @@
+// OpenIO netdata collectors
+// Copyright (C) 2019 OpenIO SAS
+//
+// This library is free software; you can redistribute it and/or
+// modify it under the terms of the GNU Lesser General Public
+// License as published by the Free Software Foundation; either
+// version 3.0 of the License, or (at your option) any later version.
+//
+// This library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package netdata
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestFlush(t *testing.T) {
+	var buf bytes.Buffer
+	out := NewBufferedWriter(&buf)
+	c := make(chan Metric, 10)
+
+	Update("score", "OPENIO.rawx_1", "42", c)
+	Update("score", "OPENIO.rawx_2", "12", c)
+	Update("req.hits", "OPENIO.rawx_1", "3", c)
+
+	out.Printf("pending\n")
+	if buf.Len() != 0 {
+		t.Fatalf("output written before flush: %q", buf.String())
+	}
+
+	if err := Flush(c, out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "pending\n" +
+		"CHART openio.score '' 'OPENIO.SCORE' '' 'Score'\n" +
+		"CHART openio.score '' 'OPENIO.SCORE' '' 'Score'\nDIMENSION OPENIO.rawx_1 'OPENIO.rawx_1' absolute\n" +
+		"CHART openio.score '' 'OPENIO.SCORE' '' 'Score'\nDIMENSION OPENIO.rawx_2 'OPENIO.rawx_2' absolute\n" +
+		"BEGIN openio.score\nSET OPENIO.rawx_1 42\nSET OPENIO.rawx_2 12\nEND\n" +
+		"CHART openio.req_hits '' 'OPENIO.REQ HITS' '' 'Request'\n" +
+		"CHART openio.req_hits '' 'OPENIO.REQ HITS' '' 'Request'\nDIMENSION OPENIO.rawx_1 'OPENIO.rawx_1' absolute\n" +
+		"BEGIN openio.req_hits\nSET OPENIO.rawx_1 3\nEND\n"
+	if buf.String() != expected {
+		t.Fatalf("unexpected output got\n%q\nexpected\n%q\n", buf.String(), expected)
+	}
+	buf.Reset()
+
+	// A metric arriving after the flush is kept for the next cycle
+	Update("score", "OPENIO.rawx_1", "43", c)
+	if err := Flush(c, out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected = "BEGIN openio.score\nSET OPENIO.rawx_1 43\nEND\n"; buf.String() != expected {
+		t.Fatalf("unexpected output got\n%q\nexpected\n%q\n", buf.String(), expected)
+	}
+}
+
+func TestFlushLabels(t *testing.T) {
+	var buf bytes.Buffer
+	out := NewBufferedWriter(&buf)
+	c := make(chan Metric, 10)
+
+	Label("service_rawx_1", "loc", "server.1", c)
+	Label("service_rawx_1", "vol", "/mnt/hdd1", c)
+	Update("service_rawx_1", "up", "1", c)
+	if err := Flush(c, out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "CHART openio.service_rawx_1 '' 'OPENIO.SERVICE RAWX 1' '' 'Services'\n" +
+		"CLABEL 'loc' 'server.1' 1\nCLABEL 'vol' '/mnt/hdd1' 1\nCLABEL_COMMIT\n" +
+		"CHART openio.service_rawx_1 '' 'OPENIO.SERVICE RAWX 1' '' 'Services'\nDIMENSION up 'up' absolute\n" +
+		"BEGIN openio.service_rawx_1\nSET up 1\nEND\n"
+	if buf.String() != expected {
+		t.Fatalf("unexpected output got\n%q\nexpected\n%q\n", buf.String(), expected)
+	}
+	buf.Reset()
+
+	// Labels are only sent again when they change
+	Label("service_rawx_1", "loc", "server.1", c)
+	Update("service_rawx_1", "up", "0", c)
+	Flush(c, out)
+	Label("service_rawx_1", "loc", "server.2", c)
+	Update("service_rawx_1", "up", "0", c)
+	Flush(c, out)
+	expected = "BEGIN openio.service_rawx_1\nSET up 0\nEND\n" +
+		"CHART openio.service_rawx_1 '' 'OPENIO.SERVICE RAWX 1' '' 'Services'\n" +
+		"CLABEL 'loc' 'server.2' 1\nCLABEL 'vol' '/mnt/hdd1' 1\nCLABEL_COMMIT\n" +
+		"BEGIN openio.service_rawx_1\nSET up 0\nEND\n"
+	if buf.String() != expected {
+		t.Fatalf("unexpected output got\n%q\nexpected\n%q\n", buf.String(), expected)
+	}
+	buf.Reset()
+
+	// A label received without values is sent with the next update
+	Label("service_rawx_1", "version", "4.2", c)
+	Flush(c, out)
+	Update("service_rawx_1", "up", "1", c)
+	Flush(c, out)
+	expected = "CHART openio.service_rawx_1 '' 'OPENIO.SERVICE RAWX 1' '' 'Services'\n" +
+		"CLABEL 'loc' 'server.2' 1\nCLABEL 'version' '4.2' 1\nCLABEL 'vol' '/mnt/hdd1' 1\nCLABEL_COMMIT\n" +
+		"BEGIN openio.service_rawx_1\nSET up 1\nEND\n"
+	if buf.String() != expected {
+		t.Fatalf("unexpected output got\n%q\nexpected\n%q\n", buf.String(), expected)
+	}
+}
+
+func TestFlushLimits(t *testing.T) {
+	SetLimits(Limits{MaxCharts: 1, MaxDimensions: 3})
+	defer SetLimits(Limits{})
+	chartIndex = makeIndex()
+	defer func() { chartIndex = makeIndex() }()
+
+	var buf bytes.Buffer
+	out := NewBufferedWriter(&buf)
+	c := make(chan Metric, 10)
+
+	Update("container_objects", "OPENIO.a", "10", c)
+	Update("container_objects", "OPENIO.b", "500", c)
+	Update("container_objects", "OPENIO.c", "20", c)
+	Update("container_objects", "OPENIO.d", "300", c)
+	Update("container_bytes", "OPENIO.a", "1", c)
+	if err := Flush(c, out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	output := buf.String()
+	for _, expected := range []string{
+		"BEGIN openio.container_objects\nSET OPENIO.b 500\nSET OPENIO.d 300\nSET other 30\nEND\n",
+		"BEGIN netdata.netdata_test_dropped_series\nSET 'charts' = 1\nSET 'dimensions' = 3\nEND\n",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Fatalf("expected %q in output\n%s", expected, output)
+		}
+	}
+	if strings.Contains(output, "container_bytes") {
+		t.Fatalf("unexpected chart over the limit in output\n%s", output)
+	}
+	buf.Reset()
+
+	// Admitted dimensions are kept, newcomers go to the aggregate
+	Update("container_objects", "OPENIO.a", "1000", c)
+	Update("container_objects", "OPENIO.b", "1", c)
+	if err := Flush(c, out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "BEGIN openio.container_objects\nSET OPENIO.b 1\nSET other 1000\nEND\n"
+	if !strings.Contains(buf.String(), expected) {
+		t.Fatalf("expected %q in output\n%s", expected, buf.String())
+	}
+}
+
+func TestFlushLimitsRerank(t *testing.T) {
+	SetLimits(Limits{MaxDimensions: 3, RerankCycles: 2})
+	defer SetLimits(Limits{})
+	chartIndex = makeIndex()
+	defer func() { chartIndex = makeIndex() }()
+
+	var buf bytes.Buffer
+	out := NewBufferedWriter(&buf)
+	c := make(chan Metric, 10)
+	for i, cycle := range []struct {
+		values   map[string]string
+		expected string
+	}{
+		{map[string]string{"a": "10", "b": "500", "c": "20", "d": "300"}, "SET OPENIO.b 500\nSET OPENIO.d 300\nSET other 30\n"},
+		// The late high value waits for the next ranking in the aggregate
+		{map[string]string{"a": "1000", "b": "1", "c": "20", "d": "300"}, "SET OPENIO.b 1\nSET OPENIO.d 300\nSET other 1020\n"},
+		// Then it replaces the dimension with the lowest value
+		{map[string]string{"a": "1000", "b": "1", "c": "20", "d": "300"}, "SET OPENIO.a 1000\nSET OPENIO.d 300\nSET other 21\n"},
+	} {
+		buf.Reset()
+		for _, dim := range []string{"a", "b", "c", "d"} {
+			if value, ok := cycle.values[dim]; ok {
+				Update("container_objects", "OPENIO."+dim, value, c)
+			}
+		}
+		if err := Flush(c, out); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		expected := "BEGIN openio.container_objects\n" + cycle.expected + "END\n"
+		if !strings.Contains(buf.String(), expected) {
+			t.Fatalf("cycle %d: expected %q in output\n%s", i, expected, buf.String())
+		}
+	}
+	if !strings.Contains(buf.String(), "DIMENSION OPENIO.a 'OPENIO.a' absolute\n") {
+		t.Fatalf("expected the admitted dimension to be created\n%s", buf.String())
+	}
+}
+
+func TestFlushRelabel(t *testing.T) {
+	r, err := NewRelabel([]RelabelRule{
+		{ID: "^meta2_cache_bases", Action: ActionDrop},
+		{ID: "^score$", Dimension: "^OPENIO\\.rawx_2$", Action: ActionDrop},
+		{ID: "^score$", Dimension: "^OPENIO\\.(.*)$", Action: ActionRelabel, SetName: "$1"},
+		{ID: "^(.*)$", Action: ActionRelabel, SetID: "sds_$1", SetFamily: "sds"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	SetRelabel(r)
+	defer SetRelabel(nil)
+	chartIndex = makeIndex()
+	legacyCharts = make(map[string]*legacyChart)
+	defer func() {
+		chartIndex = makeIndex()
+		legacyCharts = make(map[string]*legacyChart)
+	}()
+
+	var buf bytes.Buffer
+	out := NewBufferedWriter(&buf)
+	c := make(chan Metric, 10)
+
+	Update("score", "OPENIO.rawx_1", "42", c)
+	Update("score", "OPENIO.rawx_2", "12", c)
+	Update("meta2_cache_bases_size", "OPENIO.meta2_1", "3", c)
+	if err := Flush(c, out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "CHART openio.sds_score '' 'OPENIO.SDS SCORE' '' 'sds'\n" +
+		"CHART openio.sds_score '' 'OPENIO.SDS SCORE' '' 'sds'\nDIMENSION OPENIO.rawx_1 'rawx_1' absolute\n" +
+		"BEGIN openio.sds_score\nSET OPENIO.rawx_1 42\nEND\n"
+	if buf.String() != expected {
+		t.Fatalf("unexpected output got\n%q\nexpected\n%q\n", buf.String(), expected)
+	}
+}
+
+func TestDescribeLegacy(t *testing.T) {
+	r, err := NewRelabel([]RelabelRule{
+		{Type: "^openio$", ID: "^byte_", Action: ActionDrop},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	SetRelabel(r)
+	defer SetRelabel(nil)
+	legacyCharts = make(map[string]*legacyChart)
+	defer func() { legacyCharts = make(map[string]*legacyChart) }()
+
+	charts := DescribeLegacy([]LegacyPattern{
+		{Chart: "req.<counter>", Dimension: "OPENIO.<service_id>"},
+		{Chart: "byte_used", Dimension: "OPENIO.<service_id>.<fsid>"},
+		{Chart: "req.<counter>", Dimension: "OPENIO.all"},
+	})
+	if len(charts) != 1 {
+		t.Fatalf("expected 1 chart, got %v", charts)
+	}
+	c := charts[0]
+	if c.Type != "openio" || c.ID != "req_<counter>" || c.Family != "Request" || !c.Dynamic {
+		t.Fatalf("unexpected chart %+v", c)
+	}
+	if len(c.Dimensions) != 2 || c.Dimensions[0].ID != "OPENIO.<service_id>" || c.Dimensions[1].ID != "OPENIO.all" {
+		t.Fatalf("unexpected dimensions %+v", c.Dimensions)
+	}
+}
+
+func TestRelabelConfig(t *testing.T) {
+	for _, rules := range [][]RelabelRule{
+		{{ID: "score", Action: "rename"}},
+		{{ID: "(", Action: ActionDrop}},
+		{{Dimension: "rawx", Action: ActionRelabel, SetFamily: "sds"}},
+	} {
+		if _, err := NewRelabel(rules); err == nil {
+			t.Fatalf("expected error for %+v", rules)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+// OpenIO netdata collectors
+// Copyright (C) 2019 OpenIO SAS
+//
+// This library is free software; you can redistribute it and/or
+// modify it under the terms of the GNU Lesser General Public
+// License as published by the Free Software Foundation; either
+// version 3.0 of the License, or (at your option) any later version.
+//
+// This library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package netdata
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Worker -- what a collector factory may use to set itself up
+type Worker interface {
+	AddChart(chart *Chart, collector ...Collector)
+	AddCollector(collector Collector)
+	AddTarget(collector Collector, instance string, family string, context string)
+}
+
+// Runner -- worker built from a configuration, ready to run
+type Runner interface {
+	Worker
+	Run()
+	Describe() []ChartInfo
+}
+
+// Param -- entry of the configuration schema of a registered collector
+type Param struct {
+	Name        string
+	Description string
+	Required    bool
+	Default     string
+}
+
+// Params -- configuration of a collector instance, validated against its schema
+type Params map[string]string
+
+// Factory -- adds the collectors and charts of one configured instance to a worker
+type Factory func(w Worker, params Params) error
+
+type registration struct {
+	schema  []Param
+	factory Factory
+}
+
+var registry = struct {
+	sync.RWMutex
+	collectors map[string]registration
+}{collectors: make(map[string]registration)}
+
+// Register makes a collector available to BuildWorker under name. It is meant
+// to be called from the init function of the collector package, and panics if
+// the name is already taken
+func Register(name string, schema []Param, factory Factory) {
+	registry.Lock()
+	defer registry.Unlock()
+	if factory == nil {
+		panic("netdata: Register factory is nil for " + name)
+	}
+	if _, dup := registry.collectors[name]; dup {
+		panic("netdata: Register called twice for " + name)
+	}
+	registry.collectors[name] = registration{schema: schema, factory: factory}
+}
+
+// Registered returns the sorted names of the registered collectors
+func Registered() []string {
+	registry.RLock()
+	defer registry.RUnlock()
+	var names []string
+	for name := range registry.collectors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Schema returns the configuration schema of a registered collector
+func Schema(name string) ([]Param, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+	r, ok := registry.collectors[name]
+	return r.schema, ok
+}
+
+// CollectorConfig -- instance of a registered collector
+type CollectorConfig struct {
+	Type   string `yaml:"type"`
+	Params Params `yaml:"params"`
+}
+
+// WorkerConfig -- worker settings and collector instances, e.g.
+//
+//	interval: 10
+//	splay: true
+//	collectors:
+//	  - type: redis
+//	    params:
+//	      target: tcp://127.0.0.1:6011?name=redis1
+type WorkerConfig struct {
+	Interval   int               `yaml:"interval"`
+	Splay      bool              `yaml:"splay"`
+	Collectors []CollectorConfig `yaml:"collectors"`
+}
+
+// LoadWorkerConfig reads a worker configuration from a YAML file
+func LoadWorkerConfig(path string) (WorkerConfig, error) {
+	conf := WorkerConfig{}
+	in, err := ioutil.ReadFile(path)
+	if err != nil {
+		return conf, err
+	}
+	if err := yaml.UnmarshalStrict(in, &conf); err != nil {
+		return conf, fmt.Errorf("%s: %v", path, err)
+	}
+	return conf, nil
+}
+
+// LoadWorker reads a worker configuration from a YAML file and builds its worker
+func LoadWorker(path string, interval time.Duration, writer Writer) (Runner, error) {
+	conf, err := LoadWorkerConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	return BuildWorker(conf, interval, writer)
+}
+
+// WorkerConfigFlag registers the --config flag on a plugin flag set
+func WorkerConfigFlag(fs *flag.FlagSet) *string {
+	return fs.String("config", "", "Path to a worker configuration listing the collector instances to run, instead of --targets")
+}
+
+// BuildWorker returns a worker running the configured collector instances.
+// The interval of the configuration defaults to interval
+func BuildWorker(conf WorkerConfig, interval time.Duration, writer Writer) (Runner, error) {
+	if conf.Interval > 0 {
+		interval = time.Duration(conf.Interval) * time.Second
+	}
+	w := NewWorker(interval, writer)
+	if conf.Splay {
+		w.EnableSplay()
+	}
+	for i, c := range conf.Collectors {
+		registry.RLock()
+		r, ok := registry.collectors[c.Type]
+		registry.RUnlock()
+		if !ok {
+			return nil, fmt.Errorf("collector %d: unknown type %q", i+1, c.Type)
+		}
+		params, err := validateParams(r.schema, c.Params)
+		if err != nil {
+			return nil, fmt.Errorf("collector %d (%s): %v", i+1, c.Type, err)
+		}
+		if err := r.factory(w, params); err != nil {
+			return nil, fmt.Errorf("collector %d (%s): %v", i+1, c.Type, err)
+		}
+	}
+	return w, nil
+}
+
+// validateParams rejects unknown and missing parameters, and fills defaults
+func validateParams(schema []Param, params Params) (Params, error) {
+	known := make(map[string]bool)
+	validated := make(Params)
+	for _, p := range schema {
+		known[p.Name] = true
+		value, ok := params[p.Name]
+		if !ok || value == "" {
+			if p.Required {
+				return nil, fmt.Errorf("missing parameter %q", p.Name)
+			}
+			value = p.Default
+		}
+		validated[p.Name] = value
+	}
+	for name := range params {
+		if !known[name] {
+			return nil, fmt.Errorf("unknown parameter %q", name)
+		}
+	}
+	return validated, nil
+}
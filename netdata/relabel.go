@@ -0,0 +1,233 @@
+// OpenIO netdata collectors
+// Copyright (C) 2019 OpenIO SAS
+//
+// This library is free software; you can redistribute it and/or
+// modify it under the terms of the GNU Lesser General Public
+// License as published by the Free Software Foundation; either
+// version 3.0 of the License, or (at your option) any later version.
+//
+// This library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package netdata
+
+import (
+	"fmt"
+	"io/ioutil"
+	"regexp"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Relabel actions
+const (
+	ActionDrop    = "drop"
+	ActionKeep    = "keep"
+	ActionRelabel = "relabel"
+)
+
+// RelabelRule -- filter or rewrite for the series matching the patterns.
+// Empty patterns match anything; rules with a dimension pattern apply to the
+// dimensions of the matching charts, the others to the charts themselves
+type RelabelRule struct {
+	Type      string `yaml:"type"`
+	ID        string `yaml:"id"`
+	Dimension string `yaml:"dimension"`
+	Action    string `yaml:"action"`
+
+	// Replacements for the relabel action, may reference groups of the
+	// dimension pattern for dimensions, of the ID pattern for charts
+	SetID     string `yaml:"set_id"`
+	SetName   string `yaml:"set_name"`
+	SetFamily string `yaml:"set_family"`
+}
+
+// RelabelConfig -- content of a relabel file
+type RelabelConfig struct {
+	Rules []RelabelRule `yaml:"rules"`
+}
+
+type rule struct {
+	typ, id, dim *regexp.Regexp
+	RelabelRule
+}
+
+// Relabel -- compiled rules, evaluated in order against the type, ID and
+// dimension emitted by the collector
+type Relabel struct {
+	rules []rule
+}
+
+var relabel *Relabel
+
+// labels -- chart attributes that rules may rewrite
+type labels struct {
+	id, name, family string
+}
+
+// LoadRelabel reads the rules of the plugin from a YAML file
+func LoadRelabel(path string) error {
+	in, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	conf := RelabelConfig{}
+	if err := yaml.UnmarshalStrict(in, &conf); err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	r, err := NewRelabel(conf.Rules)
+	if err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	SetRelabel(r)
+	return nil
+}
+
+// SetRelabel sets the rules applied to the series of the plugin
+func SetRelabel(r *Relabel) {
+	relabel = r
+}
+
+// NewRelabel compiles a list of rules
+func NewRelabel(rules []RelabelRule) (*Relabel, error) {
+	r := &Relabel{}
+	for i, conf := range rules {
+		switch conf.Action {
+		case ActionDrop, ActionKeep, ActionRelabel:
+		default:
+			return nil, fmt.Errorf("rule %d: unknown action %q", i+1, conf.Action)
+		}
+		if conf.Action == ActionRelabel && conf.Dimension != "" && (conf.SetID != "" || conf.SetFamily != "") {
+			return nil, fmt.Errorf("rule %d: only set_name applies to dimensions", i+1)
+		}
+		compiled := rule{RelabelRule: conf}
+		var err error
+		if compiled.typ, err = compile(conf.Type); err == nil {
+			if compiled.id, err = compile(conf.ID); err == nil {
+				compiled.dim, err = compile(conf.Dimension)
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("rule %d: %v", i+1, err)
+		}
+		r.rules = append(r.rules, compiled)
+	}
+	return r, nil
+}
+
+func compile(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+	return regexp.Compile(pattern)
+}
+
+func match(re *regexp.Regexp, value string) bool {
+	return re == nil || re.MatchString(value)
+}
+
+func expand(re *regexp.Regexp, template string, value string) string {
+	if re == nil {
+		return template
+	}
+	m := re.FindStringSubmatchIndex(value)
+	if m == nil {
+		return template
+	}
+	return string(re.ExpandString(nil, template, value, m))
+}
+
+func (r *rule) matchChart(typ, id string) bool {
+	return match(r.typ, typ) && match(r.id, id)
+}
+
+// chart applies the chart rules and returns false if the chart is dropped
+func (r *Relabel) chart(typ string, l *labels) bool {
+	if r == nil {
+		return true
+	}
+	id := l.id
+	for _, rl := range r.rules {
+		if rl.dim != nil {
+			continue
+		}
+		matched := rl.matchChart(typ, id)
+		switch {
+		case rl.Action == ActionDrop && matched:
+			return false
+		case rl.Action == ActionKeep && !matched:
+			return false
+		case rl.Action == ActionRelabel && matched:
+			re := rl.id
+			if re == nil {
+				re = rl.typ
+			}
+			if rl.SetID != "" {
+				l.id = expand(re, rl.SetID, id)
+			}
+			if rl.SetName != "" {
+				l.name = expand(re, rl.SetName, id)
+			}
+			if rl.SetFamily != "" {
+				l.family = expand(re, rl.SetFamily, id)
+			}
+		}
+	}
+	return true
+}
+
+// dimension applies the dimension rules of a chart and returns false if the
+// dimension is dropped
+func (r *Relabel) dimension(typ, id, dim string, name *string) bool {
+	if r == nil {
+		return true
+	}
+	for _, rl := range r.rules {
+		if rl.dim == nil || !rl.matchChart(typ, id) {
+			continue
+		}
+		matched := rl.dim.MatchString(dim)
+		switch {
+		case rl.Action == ActionDrop && matched:
+			return false
+		case rl.Action == ActionKeep && !matched:
+			return false
+		case rl.Action == ActionRelabel && matched && rl.SetName != "":
+			*name = expand(rl.dim, rl.SetName, dim)
+		}
+	}
+	return true
+}
+
+// relabelChart rewrites a chart and filters its dimensions, it returns false
+// if the whole chart is dropped
+func (r *Relabel) relabelChart(c *Chart) bool {
+	if r == nil {
+		return true
+	}
+	l := labels{id: c.ID, name: c.Name, family: c.Family}
+	if !r.chart(c.Type, &l) {
+		return false
+	}
+	var index []string
+	for _, dimID := range c.dimensionsIndex {
+		dim := c.dimensions[dimID]
+		if !r.dimension(c.Type, c.ID, dimID, &dim.name) {
+			delete(c.dimensions, dimID)
+			continue
+		}
+		c.dimensions[dimID] = dim
+		index = append(index, dimID)
+	}
+	if len(index) == 0 {
+		return false
+	}
+	c.dimensionsIndex = index
+	c.ID, c.Name, c.Family = l.id, l.name, l.family
+	return true
+}
@@ -0,0 +1,45 @@
+// OpenIO netdata collectors
+// Copyright (C) 2019 OpenIO SAS
+//
+// This library is free software; you can redistribute it and/or
+// modify it under the terms of the GNU Lesser General Public
+// License as published by the Free Software Foundation; either
+// version 3.0 of the License, or (at your option) any later version.
+//
+// This library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package netdata
+
+import (
+	"hash/fnv"
+	"os"
+	"time"
+)
+
+// SplayOffset returns a delay within interval derived from the host name, so
+// that the nodes of a cluster collect at different moments
+func SplayOffset(interval time.Duration) time.Duration {
+	if interval <= 0 {
+		return 0
+	}
+	hostname, _ := os.Hostname()
+	h := fnv.New64a()
+	h.Write([]byte(hostname + "/" + pluginName()))
+	return time.Duration(h.Sum64() % uint64(interval))
+}
+
+// UntilNextSlot returns how long to wait for the next collection slot. Slots
+// are every interval, shifted by offset, so that the period does not drift
+// with the time spent collecting
+func UntilNextSlot(now time.Time, interval time.Duration, offset time.Duration) time.Duration {
+	if interval <= 0 {
+		return 0
+	}
+	return interval - time.Duration((now.UnixNano()-int64(offset))%int64(interval))
+}
@@ -0,0 +1,124 @@
+// OpenIO netdata collectors
+// Copyright (C) 2019 OpenIO SAS
+//
+// This library is free software; you can redistribute it and/or
+// modify it under the terms of the GNU Lesser General Public
+// License as published by the Free Software Foundation; either
+// version 3.0 of the License, or (at your option) any later version.
+//
+// This library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package netdata
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Error classes reported on the last_error chart of targets
+const (
+	ErrorRefused  = "refused"
+	ErrorTimeout  = "timeout"
+	ErrorProtocol = "protocol"
+	ErrorParse    = "parse"
+)
+
+var errorClasses = []string{ErrorRefused, ErrorTimeout, ErrorProtocol, ErrorParse}
+
+// Target -- collectors of a remote service implement it to report the time
+// spent connecting to the service on the last collection
+type Target interface {
+	ConnectTime() time.Duration
+}
+
+type targetError struct {
+	class string
+	msg   string
+}
+
+func (e *targetError) Error() string {
+	return e.msg
+}
+
+// ProtocolErrorf returns an error for a target answering unexpectedly
+func ProtocolErrorf(format string, v ...interface{}) error {
+	return &targetError{class: ErrorProtocol, msg: fmt.Sprintf(format, v...)}
+}
+
+// ParseErrorf returns an error for a target answer that could not be parsed
+func ParseErrorf(format string, v ...interface{}) error {
+	return &targetError{class: ErrorParse, msg: fmt.Sprintf(format, v...)}
+}
+
+// ErrorClass returns the class of a collection error
+func ErrorClass(err error) string {
+	if e, ok := err.(*targetError); ok {
+		return e.class
+	}
+	if e, ok := err.(net.Error); ok && e.Timeout() {
+		return ErrorTimeout
+	}
+	if e, ok := err.(*net.OpError); ok {
+		if se, ok := e.Err.(*os.SyscallError); ok && se.Err == syscall.ECONNREFUSED {
+			return ErrorRefused
+		}
+	}
+	if strings.Contains(err.Error(), "connection refused") {
+		return ErrorRefused
+	}
+	return ErrorProtocol
+}
+
+// AddTarget registers the availability charts of a collector: whether the
+// target is up, the time to connect to it and the class of the last error
+func (w *worker) AddTarget(collector Collector, instance string, family string, context string) {
+	w.targets[collector] = true
+
+	up := NewChart(instance, "up", "", "Target availability", "up", family, context+".up")
+	up.AddDimension("target_up", "up", AbsoluteAlgorithm)
+	w.AddChart(up, collector)
+
+	if _, ok := collector.(Target); ok {
+		connect := NewChart(instance, "connect_time", "", "Connect time", "microseconds", family, context+".connect_time")
+		connect.AddDimension("target_connect_time", "connect", AbsoluteAlgorithm)
+		w.AddChart(connect, collector)
+	}
+
+	lastError := NewChart(instance, "last_error", "", "Last error", "error", family, context+".last_error")
+	for _, class := range errorClasses {
+		lastError.AddDimension("target_error_"+class, class, AbsoluteAlgorithm)
+	}
+	w.AddChart(lastError, collector)
+}
+
+// targetData returns the collected data along with the availability of the target
+func targetData(collector Collector, data map[string]string, err error) map[string]string {
+	res := make(map[string]string, len(data)+len(errorClasses)+2)
+	for k, v := range data {
+		res[k] = v
+	}
+	for _, class := range errorClasses {
+		res["target_error_"+class] = "0"
+	}
+	if err != nil {
+		res["target_up"] = "0"
+		res["target_error_"+ErrorClass(err)] = "1"
+		return res
+	}
+	res["target_up"] = "1"
+	if t, ok := collector.(Target); ok {
+		res["target_connect_time"] = strconv.FormatInt(t.ConnectTime().Nanoseconds()/1e3, 10)
+	}
+	return res
+}
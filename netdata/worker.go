@@ -18,7 +18,7 @@ package netdata
 
 import (
 	"fmt"
-	"log"
+	"oionetdata/logger"
 	"time"
 )
 
@@ -32,6 +32,9 @@ type worker struct {
 
 	runs int
 
+	splay  bool
+	offset time.Duration
+
 	startRun time.Time
 
 	lastUpdate time.Time
@@ -40,6 +43,8 @@ type worker struct {
 
 	charts      Charts
 	chartsIndex map[Collector][]string
+	dropped     map[Collector][]*Chart
+	targets     map[Collector]bool
 
 	writer Writer
 
@@ -53,6 +58,8 @@ func NewWorker(interval time.Duration, writer Writer, collectors ...Collector) *
 		writer:      writer,
 		charts:      make(map[string]*Chart),
 		chartsIndex: make(map[Collector][]string),
+		dropped:     make(map[Collector][]*Chart),
+		targets:     make(map[Collector]bool),
 	}
 	if len(collectors) > 0 {
 		w.collector = collectors[0]
@@ -67,6 +74,12 @@ func (w *worker) SetCollector(collector Collector) {
 	w.collectors = append(w.collectors, collector)
 }
 
+// EnableSplay aligns collections on slots shifted by a per host offset
+func (w *worker) EnableSplay() {
+	w.splay = true
+	w.offset = SplayOffset(w.interval)
+}
+
 func (w *worker) AddCollector(collector Collector) {
 	w.collectors = append(w.collectors, collector)
 }
@@ -76,7 +89,14 @@ func (w *worker) AddChart(chart *Chart, params ...Collector) {
 	if len(params) > 0 {
 		collector = params[0]
 	}
+	if !relabel.relabelChart(chart) {
+		return
+	}
 	chartID := fmt.Sprintf("%s_%s", chart.ID, chart.Family)
+	if _, ok := w.charts[chartID]; !ok && limits.MaxCharts > 0 && len(w.charts) >= limits.MaxCharts {
+		w.dropped[collector] = append(w.dropped[collector], chart)
+		return
+	}
 	w.indexChart(chartID, collector)
 	w.charts[chartID] = chart
 }
@@ -90,7 +110,12 @@ func (w *worker) SinceLastRun() time.Duration {
 }
 
 func (w *worker) Run() {
-	log.Printf("Start interval: %v, retries: %v", w.interval, w.maxRetries)
+	logger.Info("Start", "interval", w.interval, "retries", w.maxRetries)
+
+	if w.splay {
+		logger.Info("Splay offset", "offset", w.offset)
+		w.sleep(UntilNextSlot(time.Now(), w.interval, w.offset))
+	}
 
 	for {
 		w.process()
@@ -105,6 +130,9 @@ func (w *worker) process() {
 		sinceUpdate = w.startRun.Sub(w.lastUpdate)
 	}
 	updated, _ := w.update(sinceUpdate)
+	if err := w.writer.Flush(); err != nil {
+		logger.Error("Failed to write output", "err", err)
+	}
 
 	w.runs++
 
@@ -113,10 +141,14 @@ func (w *worker) process() {
 	} else {
 		w.elapsed = time.Since(w.startRun)
 		w.lastUpdate = w.startRun
-		log.Printf("elapsed: %v", w.elapsed)
+		logger.Debug("Collection done", "elapsed", w.elapsed)
 	}
 
-	w.sleep(w.interval)
+	if w.splay {
+		w.sleep(UntilNextSlot(time.Now(), w.interval, w.offset))
+	} else {
+		w.sleep(w.interval)
+	}
 }
 
 func (w *worker) sleep(sleepTime time.Duration) {
@@ -125,28 +157,51 @@ func (w *worker) sleep(sleepTime time.Duration) {
 
 func (w *worker) update(interval time.Duration) (bool, error) {
 	updated := false
+	droppedCharts, droppedDims := 0, 0
 
 	for _, collector := range w.collectors {
 		data, err := collector.Collect()
 		if err != nil {
-			log.Printf("Failed to update: %v", err)
-			continue
+			logger.Warn("Failed to update", "err", err)
+			if !w.targets[collector] {
+				continue
+			}
+		}
+		if w.targets[collector] {
+			data = targetData(collector, data, err)
+		}
+
+		for _, chart := range w.dropped[collector] {
+			droppedCharts++
+			for _, dim := range chart.dimensionsIndex {
+				if _, ok := data[dim]; ok {
+					droppedDims++
+				}
+			}
 		}
 
 		if _, ok := w.chartsIndex[collector]; ok {
 			for _, chartID := range w.chartsIndex[collector] {
 				chart := w.charts[chartID]
-				updated = chart.Update(data, interval, w.writer)
+				values := data
+				if limits.MaxDimensions > 0 {
+					var other int
+					values, other = chart.limitDimensions(data)
+					droppedDims += other
+				}
+				updated = chart.Update(values, interval, w.writer)
 			}
 		} else {
-			log.Printf("Failed to update: collector not found")
-			log.Println(collector)
-			log.Println("Charts index", w.chartsIndex)
+			logger.Error("Failed to update: collector not found", "collector", fmt.Sprintf("%T", collector))
 		}
 
 		if !updated {
-			log.Printf("DEBUG: no charts updated")
+			logger.Debug("No charts updated")
 		}
 	}
+
+	if limits.enabled() {
+		reportDropped(w.writer, droppedCharts, droppedDims)
+	}
 	return updated, nil
 }
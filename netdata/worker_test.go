@@ -18,6 +18,10 @@ package netdata
 
 import (
 	"bytes"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -79,6 +83,159 @@ func TestWorker(t *testing.T) {
 
 }
 
+type testTarget struct {
+	data map[string]string
+	err  error
+}
+
+func (c *testTarget) Collect() (map[string]string, error) {
+	return c.data, c.err
+}
+
+func (c *testTarget) ConnectTime() time.Duration {
+	return 1500 * time.Microsecond
+}
+
+func TestWorkerTarget(t *testing.T) {
+	collector := &testTarget{data: map[string]string{"keys": "3"}}
+	var buf bytes.Buffer
+	w := NewWorker(time.Millisecond, &writer{out: &buf})
+	w.AddCollector(collector)
+	w.AddTarget(collector, "redis.test", "redis.test", "redis")
+	w.process()
+	for _, expected := range []string{
+		"BEGIN redis.test.up\nSET 'target_up' = 1\nEND\n",
+		"BEGIN redis.test.connect_time\nSET 'target_connect_time' = 1500\nEND\n",
+		"BEGIN redis.test.last_error\nSET 'target_error_refused' = 0\nSET 'target_error_timeout' = 0\nSET 'target_error_protocol' = 0\nSET 'target_error_parse' = 0\nEND\n",
+	} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Fatalf("expected %q in output\n%s", expected, buf.String())
+		}
+	}
+	buf.Reset()
+
+	collector.data = nil
+	collector.err = ParseErrorf("garbage")
+	validateOutput(t, w, &buf, "BEGIN redis.test.up\nSET 'target_up' = 0\nEND\n"+
+		"BEGIN redis.test.last_error\nSET 'target_error_refused' = 0\nSET 'target_error_timeout' = 0\nSET 'target_error_protocol' = 0\nSET 'target_error_parse' = 1\nEND\n")
+}
+
+func TestWorkerRelabel(t *testing.T) {
+	r, err := NewRelabel([]RelabelRule{
+		{Type: "^fuse$", ID: "^ops$", Dimension: "^(forget|rename)$", Action: ActionDrop},
+		{Type: "^fuse$", ID: "^(.*)$", Action: ActionRelabel, SetID: "oiofs_$1", SetFamily: "oiofs"},
+		{Type: "^fuse$", ID: "^cache$", Action: ActionKeep},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	SetRelabel(r)
+	defer SetRelabel(nil)
+
+	data := map[string]string{"read": "1", "forget": "2", "hits": "3"}
+	collector := &testCollector{data}
+	var buf bytes.Buffer
+	w := NewWorker(time.Millisecond, &writer{out: &buf}, collector)
+	ops := NewChart("fuse", "ops", "", "Operations", "ops", "fuse", "")
+	ops.AddDimension("read", "read", AbsoluteAlgorithm)
+	ops.AddDimension("forget", "forget", AbsoluteAlgorithm)
+	w.AddChart(ops)
+	cache := NewChart("fuse", "cache", "", "Cache", "hits", "fuse", "")
+	cache.AddDimension("hits", "hits", AbsoluteAlgorithm)
+	w.AddChart(cache)
+
+	// ops is dropped by the keep rule, which matches the emitted ID
+	expectedOutput := "CHART fuse.oiofs_cache '' 'Cache' 'hits' 'oiofs' ''\n" +
+		"DIMENSION 'hits' 'hits' absolute\n" +
+		"BEGIN fuse.oiofs_cache\nSET 'hits' = 3\nEND\n"
+	validateOutput(t, w, &buf, expectedOutput)
+
+	// Without the keep rule, ops is renamed and loses the dropped dimension
+	r.rules = r.rules[:2]
+	w = NewWorker(time.Millisecond, &writer{out: &buf}, collector)
+	w.AddChart(ops)
+	expectedOutput = "CHART fuse.oiofs_ops '' 'Operations' 'ops' 'oiofs' ''\n" +
+		"DIMENSION 'read' 'read' absolute\n" +
+		"BEGIN fuse.oiofs_ops\nSET 'read' = 1\nEND\n"
+	validateOutput(t, w, &buf, expectedOutput)
+}
+
+func TestWorkerDescribe(t *testing.T) {
+	collector := &testCollector{map[string]string{}}
+	w := NewWorker(time.Millisecond, &writer{out: &bytes.Buffer{}}, collector)
+	ops := NewChart("fuse", "ops", "", "Operations", "ops", "fuse", "fuse.ops")
+	ops.AddDimension("read", "read", IncrementalAlgorithm)
+	ops.AddDimension("write", "written", IncrementalAlgorithm)
+	w.AddChart(ops)
+	w.AddChart(ops)
+
+	var buf bytes.Buffer
+	if err := WriteDescription(&buf, FormatMarkdown, w.Describe()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "| Chart | Title | Units | Family | Context | Dimensions |\n" +
+		"|---|---|---|---|---|---|\n" +
+		"| `fuse.ops` | Operations | ops | fuse | fuse.ops | `read` incremental<br>`write` (written) incremental |\n"
+	if buf.String() != expected {
+		t.Fatalf("unexpected markdown got\n%s\nexpected\n%s", buf.String(), expected)
+	}
+
+	buf.Reset()
+	if err := WriteDescription(&buf, FormatJSON, w.Describe()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(buf.String(), `"id": "write",
+        "name": "written",
+        "algorithm": "incremental"`) {
+		t.Fatalf("unexpected json %s", buf.String())
+	}
+
+	if err := WriteDescription(&buf, "xml", nil); err == nil {
+		t.Fatalf("expected error on unknown format")
+	}
+}
+
+func TestErrorClass(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen error: %v", err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+	_, err = net.Dial("tcp", addr)
+	if class := ErrorClass(err); class != ErrorRefused {
+		t.Fatalf("unexpected class %s for %v", class, err)
+	}
+	if class := ErrorClass(ProtocolErrorf("unexpected")); class != ErrorProtocol {
+		t.Fatalf("unexpected class %s", class)
+	}
+}
+
+func TestSplay(t *testing.T) {
+	interval := 10 * time.Second
+	offset := SplayOffset(interval)
+	if offset < 0 || offset >= interval || offset != SplayOffset(interval) {
+		t.Fatalf("unexpected offset %v", offset)
+	}
+
+	base := time.Unix(1000, 0)
+	tests := []struct {
+		now      time.Time
+		offset   time.Duration
+		expected time.Duration
+	}{
+		{now: base, offset: 0, expected: interval},
+		{now: base.Add(time.Second), offset: 0, expected: 9 * time.Second},
+		{now: base, offset: 3 * time.Second, expected: 3 * time.Second},
+		{now: base.Add(5 * time.Second), offset: 3 * time.Second, expected: 8 * time.Second},
+	}
+	for _, tt := range tests {
+		if wait := UntilNextSlot(tt.now, interval, tt.offset); wait != tt.expected {
+			t.Fatalf("unexpected wait at %v with offset %v got %v expected %v", tt.now, tt.offset, wait, tt.expected)
+		}
+	}
+}
+
 func validateOutput(t *testing.T, w *worker, buf *bytes.Buffer, expectedOutput string) {
 	w.process()
 	output := buf.String()
@@ -87,3 +244,138 @@ func validateOutput(t *testing.T, w *worker, buf *bytes.Buffer, expectedOutput s
 	}
 	buf.Reset()
 }
+
+func TestBuildWorker(t *testing.T) {
+	var got Params
+	Register("test", []Param{
+		{Name: "addr", Required: true},
+		{Name: "family", Default: "general"},
+	}, func(w Worker, params Params) error {
+		got = params
+		collector := &testCollector{map[string]string{"value": "1"}}
+		w.AddCollector(collector)
+		chart := NewChart("test", params["addr"], "", "Test", "", params["family"], "")
+		chart.AddDimension("value", "value", AbsoluteAlgorithm)
+		w.AddChart(chart, collector)
+		return nil
+	})
+	defer func() {
+		registry.Lock()
+		delete(registry.collectors, "test")
+		registry.Unlock()
+	}()
+
+	var buf bytes.Buffer
+	w, err := BuildWorker(WorkerConfig{
+		Collectors: []CollectorConfig{{Type: "test", Params: Params{"addr": "a"}}},
+	}, time.Millisecond, &writer{out: &buf})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got["family"] != "general" {
+		t.Fatalf("expected default family, got %v", got)
+	}
+	validateOutput(t, w.(*worker), &buf, "CHART test.a '' 'Test' '' 'general' ''\nDIMENSION 'value' 'value' absolute\n"+
+		"BEGIN test.a\nSET 'value' = 1\nEND\n")
+
+	for _, c := range []CollectorConfig{
+		{Type: "unknown"},
+		{Type: "test"},
+		{Type: "test", Params: Params{"addr": "a", "port": "1"}},
+	} {
+		if _, err := BuildWorker(WorkerConfig{Collectors: []CollectorConfig{c}}, time.Second, &writer{out: &buf}); err == nil {
+			t.Fatalf("expected error for %+v", c)
+		}
+	}
+
+	// The --config flag of the plugins loads the same configuration from YAML
+	dir, err := ioutil.TempDir("", "netdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "collectors.yml")
+	conf := "interval: 5\ncollectors:\n  - type: test\n    params:\n      addr: b\n"
+	if err := ioutil.WriteFile(path, []byte(conf), 0644); err != nil {
+		t.Fatal(err)
+	}
+	w, err = LoadWorker(path, time.Second, &writer{out: &buf})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if charts := w.Describe(); len(charts) != 1 || charts[0].ID != "b" || w.(*worker).interval != 5*time.Second {
+		t.Fatalf("unexpected worker %+v", charts)
+	}
+	if err := ioutil.WriteFile(path, []byte("collectors:\n  - kind: test\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadWorker(path, time.Second, &writer{out: &buf}); err == nil {
+		t.Fatalf("expected error for an unknown key")
+	}
+}
+
+func TestWorkerLimits(t *testing.T) {
+	SetLimits(Limits{MaxCharts: 1})
+	defer SetLimits(Limits{})
+
+	collector := &testCollector{map[string]string{"a": "1", "b": "2", "c": "3"}}
+	var buf bytes.Buffer
+	w := NewWorker(time.Millisecond, &writer{out: &buf}, collector)
+	chart := NewChart("test", "kept", "", "Kept", "", "test", "")
+	chart.AddDimension("a", "a", AbsoluteAlgorithm)
+	w.AddChart(chart)
+	chart = NewChart("test", "dropped", "", "Dropped", "", "test", "")
+	chart.AddDimension("b", "b", AbsoluteAlgorithm)
+	chart.AddDimension("c", "c", AbsoluteAlgorithm)
+	chart.AddDimension("d", "d", AbsoluteAlgorithm)
+	w.AddChart(chart)
+
+	// Dropped series are counted on each cycle, like Flush does
+	for i := 0; i < 2; i++ {
+		buf.Reset()
+		w.process()
+		expected := "BEGIN netdata.netdata_test_dropped_series\nSET 'charts' = 1\nSET 'dimensions' = 2\nEND\n"
+		if !strings.Contains(buf.String(), expected) {
+			t.Fatalf("cycle %d: expected %q in output\n%s", i, expected, buf.String())
+		}
+	}
+}
+
+func TestWorkerLimitsRerank(t *testing.T) {
+	SetLimits(Limits{MaxDimensions: 3, RerankCycles: 2})
+	defer SetLimits(Limits{})
+
+	collector := &testCollector{map[string]string{"a": "10", "b": "500", "c": "20", "d": "300"}}
+	var buf bytes.Buffer
+	w := NewWorker(time.Millisecond, &writer{out: &buf}, collector)
+	chart := NewChart("test", "objects", "", "Objects", "", "test", "")
+	for _, dim := range []string{"a", "b", "c", "d"} {
+		chart.AddDimension(dim, dim, AbsoluteAlgorithm)
+	}
+	w.AddChart(chart)
+
+	for i, cycle := range []struct {
+		values   map[string]string
+		expected string
+	}{
+		{map[string]string{"a": "10", "b": "500", "c": "20", "d": "300"}, "SET 'b' = 500\nSET 'd' = 300\nSET 'other' = 30\n"},
+		// The late high value waits for the next ranking in the aggregate
+		{map[string]string{"a": "1000", "b": "1", "c": "20", "d": "300"}, "SET 'b' = 1\nSET 'd' = 300\nSET 'other' = 1020\n"},
+		// Then it replaces the dimension with the lowest value
+		{map[string]string{"a": "1000", "b": "1", "c": "20", "d": "300"}, "SET 'a' = 1000\nSET 'd' = 300\nSET 'other' = 21\n"},
+	} {
+		buf.Reset()
+		collector.data = cycle.values
+		w.process()
+		expected := "BEGIN test.objects\n" + cycle.expected + "END\n"
+		if !strings.Contains(buf.String(), expected) {
+			t.Fatalf("cycle %d: expected %q in output\n%s", i, expected, buf.String())
+		}
+		if i == 0 && !strings.Contains(buf.String(), "DIMENSION 'b' 'b' absolute\nDIMENSION 'd' 'd' absolute\nDIMENSION 'other' 'other' absolute\n") {
+			t.Fatalf("expected only the admitted dimensions to be declared\n%s", buf.String())
+		}
+	}
+	if !strings.Contains(buf.String(), "DIMENSION 'a' 'a' absolute\n") {
+		t.Fatalf("expected the chart to be defined again with the admitted dimension\n%s", buf.String())
+	}
+}
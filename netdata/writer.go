@@ -17,20 +17,23 @@
 package netdata
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"os"
 	"sync"
 )
 
+// Writer -- sink for plugin protocol output
 type Writer interface {
 	Printf(format string, v ...interface{})
+	Flush() error
 }
 
+// NewDefaultWriter returns a writer that buffers one collection cycle and
+// sends it to stdout on Flush
 func NewDefaultWriter() Writer {
-	return &writer{
-		out: os.Stdout,
-	}
+	return NewBufferedWriter(os.Stdout)
 }
 
 type writer struct {
@@ -43,3 +46,39 @@ func (w *writer) Printf(format string, v ...interface{}) {
 	fmt.Fprintf(w.out, format, v...)
 	w.Unlock()
 }
+
+func (w *writer) Flush() error {
+	return nil
+}
+
+type bufferedWriter struct {
+	sync.Mutex
+	buf bytes.Buffer
+	out io.Writer
+}
+
+// NewBufferedWriter returns a writer that keeps output in memory until Flush
+// is called, so that lines printed from concurrent goroutines during a cycle
+// are written out in a single call and never interleave
+func NewBufferedWriter(out io.Writer) Writer {
+	return &bufferedWriter{
+		out: out,
+	}
+}
+
+func (w *bufferedWriter) Printf(format string, v ...interface{}) {
+	w.Lock()
+	fmt.Fprintf(&w.buf, format, v...)
+	w.Unlock()
+}
+
+func (w *bufferedWriter) Flush() error {
+	w.Lock()
+	defer w.Unlock()
+	if w.buf.Len() == 0 {
+		return nil
+	}
+	_, err := w.out.Write(w.buf.Bytes())
+	w.buf.Reset()
+	return err
+}
@@ -19,8 +19,7 @@ package oiofs
 import (
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"net/http"
+	"oionetdata/util"
 	"strconv"
 	"strings"
 )
@@ -98,19 +97,13 @@ func NewCollector(endpoint Endpoint, full bool) *collector {
 func (c *collector) Collect() (map[string]string, error) {
 	// TODO: support v2
 
-	r, err := http.Get(fmt.Sprintf("http://%s/stats", c.endpoint.URL))
-	if err != nil {
-		return nil, err
-	}
-	defer r.Body.Close()
-
-	body, err := ioutil.ReadAll(r.Body)
+	body, err := util.HTTPGet(util.URL(c.endpoint.URL, "/stats"))
 	if err != nil {
 		return nil, err
 	}
 
 	var rs interface{}
-	if err := json.Unmarshal(body, &rs); err != nil {
+	if err := json.Unmarshal([]byte(body), &rs); err != nil {
 		return nil, err
 	}
 
@@ -0,0 +1,57 @@
+// OpenIO netdata collectors
+// Copyright (C) 2019 OpenIO SAS
+//
+// This library is free software; you can redistribute it and/or
+// modify it under the terms of the GNU Lesser General Public
+// License as published by the Free Software Foundation; either
+// version 3.0 of the License, or (at your option) any later version.
+//
+// This library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package oiofs
+
+import (
+	"oionetdata/util"
+	"strings"
+)
+
+type simulator struct {
+	sim       *util.Simulation
+	whitelist map[string]int64
+}
+
+// NewSimulatedCollector returns a collector producing synthetic stats with the
+// keys NewCollector would gather from the endpoint, without querying it
+func NewSimulatedCollector(endpoint Endpoint, full bool) *simulator {
+	return &simulator{
+		sim:       util.NewSimulation("oiofs/" + endpoint.URL),
+		whitelist: NewCollector(endpoint, full).whitelist,
+	}
+}
+
+func (s *simulator) Collect() (map[string]string, error) {
+	data := map[string]string{}
+	for key := range s.whitelist {
+		switch {
+		case strings.HasPrefix(key, "cache_chunk_avg_age"):
+			data[key] = s.sim.Gauge(key, 1e7, 6e7)
+		case strings.HasPrefix(key, "cache_chunk_"):
+			data[key] = s.sim.Gauge(key, 1e3, 1e9)
+		case strings.HasSuffix(key, "_byte"):
+			data[key] = s.sim.Counter(key, 1e6)
+		case strings.HasSuffix(key, "_us"):
+			data[key] = s.sim.Counter(key, 1e4)
+		case strings.HasSuffix(key, "_failed"):
+			data[key] = s.sim.Counter(key, 0.1)
+		default:
+			data[key] = s.sim.Counter(key, 20)
+		}
+	}
+	return data, nil
+}
@@ -0,0 +1,100 @@
+// OpenIO netdata collectors
+// Copyright (C) 2019 OpenIO SAS
+//
+// This library is free software; you can redistribute it and/or
+// modify it under the terms of the GNU Lesser General Public
+// License as published by the Free Software Foundation; either
+// version 3.0 of the License, or (at your option) any later version.
+//
+// This library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package openio
+
+import (
+	"oionetdata/netdata"
+)
+
+/*
+Charts - patterns of the charts sent by Collect for a namespace
+*/
+func Charts(ns string) []netdata.LegacyPattern {
+	service := ns + ".<service_id>"
+	patterns := []netdata.LegacyPattern{
+		{Chart: "score", Dimension: ns + ".<type>_<service_id>"},
+		// stat.* tags, such as space, cpu and io
+		{Chart: "stat_<tag>", Dimension: ns + ".<type>_<service_id>"},
+		{Chart: "service_" + ns + ".<type>_<service_id>", Dimension: "up"},
+		{Chart: "service_" + ns + ".<type>_<service_id>", Dimension: "locked"},
+		{Chart: "services_" + ns + "_<type>", Dimension: "up"},
+		{Chart: "services_" + ns + "_<type>", Dimension: "down"},
+		{Chart: "services_" + ns + "_<type>", Dimension: "locked"},
+		{Chart: "proxy_" + ns, Dimension: "index"},
+		{Chart: "proxy_" + ns, Dimension: "candidates"},
+	}
+	for _, sType := range []string{"rawx", "rdir", "account", "oioproxy", "sqlx"} {
+		prefix := sType + "_"
+		patterns = append(patterns,
+			netdata.LegacyPattern{Chart: prefix + "requests_" + service, Dimension: "<method>"},
+			netdata.LegacyPattern{Chart: prefix + "latency_" + service, Dimension: "<method>"},
+			netdata.LegacyPattern{Chart: prefix + "latency_" + service, Dimension: "all"},
+			netdata.LegacyPattern{Chart: prefix + "responses_" + service, Dimension: "<class>"},
+			netdata.LegacyPattern{Chart: prefix + "codes_" + service, Dimension: "<code>"},
+			netdata.LegacyPattern{Chart: prefix + "bytes_" + service, Dimension: "read"},
+			netdata.LegacyPattern{Chart: prefix + "bytes_" + service, Dimension: "written"},
+		)
+	}
+	patterns = append(patterns,
+		// Counters of the meta /forward/stats and other service counters, as rates
+		netdata.LegacyPattern{Chart: "req.<counter>", Dimension: service},
+		netdata.LegacyPattern{Chart: "rep.<counter>", Dimension: service},
+		// Gauges of the service stats
+		netdata.LegacyPattern{Chart: "<type>_<gauge>", Dimension: service},
+	)
+	for _, dim := range []string{"byte_avail", "byte_used", "byte_free", "inodes_free", "inodes_used"} {
+		patterns = append(patterns, netdata.LegacyPattern{Chart: dim, Dimension: service + ".<fsid>"})
+	}
+	// Block device backing the volume of a service
+	patterns = append(patterns,
+		netdata.LegacyPattern{Chart: "disk_ops_" + service, Dimension: "read"},
+		netdata.LegacyPattern{Chart: "disk_ops_" + service, Dimension: "write"},
+		netdata.LegacyPattern{Chart: "disk_bytes_" + service, Dimension: "read"},
+		netdata.LegacyPattern{Chart: "disk_bytes_" + service, Dimension: "written"},
+		netdata.LegacyPattern{Chart: "disk_latency_" + service, Dimension: "read"},
+		netdata.LegacyPattern{Chart: "disk_latency_" + service, Dimension: "write"},
+		netdata.LegacyPattern{Chart: "disk_queue_" + service, Dimension: "in_flight"},
+		netdata.LegacyPattern{Chart: "disk_queue_" + service, Dimension: "avg"},
+		netdata.LegacyPattern{Chart: "disk_util_" + service, Dimension: "busy"},
+	)
+	for _, sType := range []string{"meta0", "meta1", "meta2"} {
+		// Fields of the /forward/info document, discovered at runtime
+		patterns = append(patterns,
+			netdata.LegacyPattern{Chart: sType + "_cache_bases_<field>", Dimension: service},
+			netdata.LegacyPattern{Chart: sType + "_elections_<field>", Dimension: service},
+			netdata.LegacyPattern{Chart: sType + "_<section>_<field>", Dimension: service},
+		)
+	}
+	return patterns
+}
+
+/*
+ClusterCharts - patterns of the charts sent by CollectCluster for a namespace
+*/
+func ClusterCharts(ns string) []netdata.LegacyPattern {
+	patterns := []netdata.LegacyPattern{
+		{Chart: "proxy_" + ns, Dimension: "index"},
+		{Chart: "proxy_" + ns, Dimension: "candidates"},
+	}
+	for _, dim := range []string{"count", "zero_score", "locked"} {
+		patterns = append(patterns, netdata.LegacyPattern{Chart: "cluster_" + ns + "_<type>_services", Dimension: dim})
+	}
+	for _, dim := range []string{"min", "avg", "max"} {
+		patterns = append(patterns, netdata.LegacyPattern{Chart: "cluster_" + ns + "_<type>_score", Dimension: dim})
+	}
+	return patterns
+}
@@ -0,0 +1,132 @@
+// OpenIO netdata collectors
+// Copyright (C) 2019 OpenIO SAS
+//
+// This library is free software; you can redistribute it and/or
+// modify it under the terms of the GNU Lesser General Public
+// License as published by the Free Software Foundation; either
+// version 3.0 of the License, or (at your option) any later version.
+//
+// This library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package openio
+
+import (
+	"fmt"
+	"io/ioutil"
+	"oionetdata/logger"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"gopkg.in/yaml.v2"
+)
+
+// SDSConf -- directory of the service configurations, where local services are
+// discovered when the conscience cannot be reached
+var SDSConf = "/etc/oio/sds"
+
+// localServices -- addresses of the local services, per type, from the last
+// successful conscience listing of each namespace
+var localServices = struct {
+	sync.Mutex
+	ns map[string]map[string][]string
+}{ns: make(map[string]map[string][]string)}
+
+// watchFile -- conscience agent registration of a service, in
+// <SDSConf>/<ns>/watch/<service>.yml
+type watchFile struct {
+	Host string `yaml:"host"`
+	Port int    `yaml:"port"`
+	Type string `yaml:"type"`
+}
+
+func cacheLocalServices(ns string, sType string, addrs []string) {
+	localServices.Lock()
+	defer localServices.Unlock()
+	if _, ok := localServices.ns[ns]; !ok {
+		localServices.ns[ns] = make(map[string][]string)
+	}
+	localServices.ns[ns][sType] = addrs
+}
+
+func cachedLocalServices(ns string, sType string) ([]string, bool) {
+	localServices.Lock()
+	defer localServices.Unlock()
+	addrs, ok := localServices.ns[ns][sType]
+	return addrs, ok
+}
+
+// snapshotLocalServices returns a copy of the cache, e.g. to persist it
+func snapshotLocalServices() map[string]map[string][]string {
+	localServices.Lock()
+	defer localServices.Unlock()
+	snapshot := make(map[string]map[string][]string, len(localServices.ns))
+	for ns, services := range localServices.ns {
+		snapshot[ns] = copyServices(services)
+	}
+	return snapshot
+}
+
+// restoreLocalServices loads a cache saved by snapshotLocalServices
+func restoreLocalServices(saved map[string]map[string][]string) {
+	for ns, services := range saved {
+		for sType, addrs := range services {
+			cacheLocalServices(ns, sType, addrs)
+		}
+	}
+}
+
+func copyServices(services map[string][]string) map[string][]string {
+	copied := make(map[string][]string, len(services))
+	for sType, addrs := range services {
+		copied[sType] = append([]string(nil), addrs...)
+	}
+	return copied
+}
+
+// fallbackServices returns the local services of the last conscience listing,
+// or those registered in the watch files if the conscience never answered
+func fallbackServices(ns string) map[string][]string {
+	localServices.Lock()
+	cached := copyServices(localServices.ns[ns])
+	localServices.Unlock()
+	if len(cached) > 0 {
+		return cached
+	}
+	services, err := discoverServices(SDSConf, ns)
+	if err != nil {
+		logger.Warn("Could not discover local services", "ns", ns, "err", err)
+	}
+	return services
+}
+
+// discoverServices reads the watch files of the namespace
+func discoverServices(basePath string, ns string) (map[string][]string, error) {
+	files, err := filepath.Glob(filepath.Join(basePath, ns, "watch", "*.yml"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	services := make(map[string][]string)
+	for _, file := range files {
+		in, err := ioutil.ReadFile(file)
+		if err != nil {
+			return services, err
+		}
+		watch := watchFile{}
+		if err := yaml.Unmarshal(in, &watch); err != nil {
+			return services, fmt.Errorf("%s: %v", file, err)
+		}
+		if watch.Type == "" || watch.Host == "" || watch.Port == 0 {
+			continue
+		}
+		services[watch.Type] = append(services[watch.Type], fmt.Sprintf("%s:%d", watch.Host, watch.Port))
+	}
+	return services, nil
+}
@@ -19,13 +19,15 @@ package openio
 import (
 	"encoding/json"
 	"fmt"
-	"log"
+	"math"
+	"oionetdata/logger"
 	"oionetdata/netdata"
 	"oionetdata/util"
 	"path"
+	"regexp"
 	"strconv"
 	"strings"
-	"sync"
+	"time"
 )
 
 type serviceType []string
@@ -34,36 +36,76 @@ type serviceInfo []struct {
 	Addr  string
 	Score int
 	Local bool
+	Tags  serviceTags
 }
 
-type cntr struct {
-	sync.RWMutex
-	counter map[string]int
+// serviceTags -- tags of a service registered in the conscience
+type serviceTags struct {
+	Up    bool   `json:"tag.up"`
+	Lock  bool   `json:"tag.lock"`
+	Loc   string `json:"tag.loc"`
+	Slots string `json:"tag.slots"`
+	Vol   string `json:"tag.vol"`
+
+	// Numeric stat.* tags, such as space, cpu and io from which the score is
+	// computed, by name without prefix; absent until the service reports them
+	Stats map[string]float64 `json:"-"`
 }
 
-func makeCounter() *cntr {
-	return &cntr{
-		counter: make(map[string]int),
+// UnmarshalJSON decodes the known tags, and every numeric stat.* tag
+func (t *serviceTags) UnmarshalJSON(b []byte) error {
+	type tags serviceTags
+	if err := json.Unmarshal(b, (*tags)(t)); err != nil {
+		return err
+	}
+	all := make(map[string]interface{})
+	if err := json.Unmarshal(b, &all); err != nil {
+		return err
+	}
+	t.Stats = make(map[string]float64)
+	for name, value := range all {
+		if v, ok := value.(float64); ok && strings.HasPrefix(name, "stat.") {
+			t.Stats[strings.TrimPrefix(name, "stat.")] = v
+		}
 	}
+	return nil
+}
+
+var rates = util.NewRates()
+
+type state struct {
+	store *util.StateStore
 }
 
-func (c *cntr) setCounter(key string, value int) {
-	c.Lock()
-	defer c.Unlock()
-	c.counter[key] = value
+// savedState -- rate counters and local services kept across plugin reloads
+type savedState struct {
+	Rates    map[string]util.Sample         `json:"rates"`
+	Services map[string]map[string][]string `json:"services"`
 }
 
-func (c *cntr) getCounter(key string) (int, bool) {
-	c.RLock()
-	defer c.RUnlock()
-	v, ok := c.counter[key]
-	return v, ok
+// NewState returns the hooks persisting rate counters and the local services
+// of the last conscience listing across plugin reloads
+func NewState(store *util.StateStore) *state {
+	return &state{store: store}
 }
 
-var counter = makeCounter()
+func (s *state) Load() error {
+	saved := savedState{}
+	ok, err := s.store.Load(&saved)
+	if err != nil || !ok {
+		return err
+	}
+	rates.Restore(saved.Rates)
+	restoreLocalServices(saved.Services)
+	return nil
+}
 
-// CollectInterval -- collection interval for derivatives
-var CollectInterval = 10
+func (s *state) Save() error {
+	return s.store.Save(savedState{
+		Rates:    rates.Snapshot(),
+		Services: snapshotLocalServices(),
+	})
+}
 
 // ProxyAddr returns the proxy address from namespace configuration
 func ProxyAddr(basePath string, ns string) (string, error) {
@@ -78,6 +120,22 @@ func ProxyAddr(basePath string, ns string) (string, error) {
 	return "", fmt.Errorf("no proxy address found for %s", ns)
 }
 
+// ProxyAddrs returns the proxy addresses from namespace configuration, where
+// the "proxy" key may list several endpoints separated by commas
+func ProxyAddrs(basePath string, ns string) ([]string, error) {
+	addr, err := ProxyAddr(basePath, ns)
+	if err != nil {
+		return nil, err
+	}
+	var addrs []string
+	for _, a := range strings.Split(addr, ",") {
+		if a = strings.TrimSpace(a); a != "" {
+			addrs = append(addrs, a)
+		}
+	}
+	return addrs, nil
+}
+
 // ZookeeperAddr retrieves local zookeeper address from namespace configuration
 func ZookeeperAddr(basePath string, ns string) (string, error) {
 	const clusterSep = ";"
@@ -99,59 +157,223 @@ func ZookeeperAddr(basePath string, ns string) (string, error) {
 	return "", fmt.Errorf("no local zookeeper address found for %s", ns)
 }
 
-func diffCounter(metric string, sid string, value string) string {
-	res := ""
-	curr, err := strconv.Atoi(value)
+func diffCounter(metric string, sid string, value string, now time.Time) string {
+	rate, ok := counterRate(metric, sid, value, now)
+	if !ok {
+		return ""
+	}
+	return round(rate)
+}
+
+func counterRate(metric string, sid string, value string, now time.Time) (float64, bool) {
+	curr, err := util.ParseCounter(value)
 	if err != nil {
-		return res
+		return 0, false
 	}
+	return rates.Rate(metric+sid, curr, now)
+}
+
+// round formats a value for the plugin protocol, which only accepts integers
+func round(value float64) string {
+	return strconv.FormatInt(int64(math.Round(value)), 10)
+}
 
-	if prev, ok := counter.getCounter(metric + sid); ok {
-		res = strconv.Itoa((curr - prev) / CollectInterval)
+// formatNumber formats a number read from a service for the plugin protocol.
+// Integers are kept as is, so that values over 2^53 are not altered through
+// float64, and the others are rounded
+func formatNumber(value string) (string, bool) {
+	if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return strconv.FormatInt(i, 10), true
 	}
-	counter.setCounter(metric+sid, curr)
-	return res
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return "", false
+	}
+	return round(f), true
 }
 
 /*
 Collect - collect openio metrics
 */
 func Collect(proxyURL string, ns string, c chan netdata.Metric) {
-	sType, err := serviceTypes(proxyURL, ns)
+	collect(proxy{url: proxyURL}, ns, c)
+}
+
+/*
+CollectProxies - collect openio metrics through the first proxy of the
+namespace that answers, and report which one was used
+*/
+func CollectProxies(proxies *Proxies, ns string, c chan netdata.Metric) {
+	proxyURL, err := proxies.Get()
+	if err != nil {
+		logger.Warn("No proxy available, collecting known local services", "ns", ns, "err", err)
+		for t, addrs := range fallbackServices(ns) {
+			collectServices(ns, t, addrs, proxy{}, false, c)
+		}
+		return
+	}
+	collect(proxy{url: proxyURL, proxies: proxies}, ns, c)
+}
+
+// collect lists the services of the namespace through the proxy, then through
+// the next available one if it fails and the proxies are set
+func collect(p proxy, ns string, c chan netdata.Metric) {
+	sType, err := serviceTypes(p, ns)
+	if err != nil && p.proxies != nil {
+		// The proxy answered, but not for the namespace
+		p.proxies.Failed(p.url)
+		if next, nextErr := p.proxies.Get(); nextErr == nil {
+			p.url = next
+			sType, err = serviceTypes(p, ns)
+		}
+	}
 	if err != nil {
-		log.Println("WARN: couldn't retrieve service types", err)
+		logger.Warn("Could not retrieve service types, collecting known local services", "ns", ns, "err", err)
+		for t, addrs := range fallbackServices(ns) {
+			collectServices(ns, t, addrs, p, false, c)
+		}
 		return
 	}
+	if p.proxies != nil {
+		p.proxies.report(c)
+	}
 	for t := range sType {
-		sInfo, err := collectScore(proxyURL, ns, sType[t], c)
+		sInfo, err := collectScore(p, ns, sType[t], c)
 		if err != nil {
-			log.Println("WARN: could not retrieve services of type", sType[t], err)
+			logger.Warn("Could not retrieve services", "ns", ns, "type", sType[t], "err", err)
+			if addrs, ok := cachedLocalServices(ns, sType[t]); ok {
+				collectServices(ns, sType[t], addrs, p, false, c)
+			}
 			continue
 		}
-		if sType[t] == "rawx" {
-			for sc := range sInfo {
-				if sInfo[sc].Local {
-					go collectRawx(ns, sInfo[sc].Addr, c)
-				}
-			}
-		} else if strings.HasPrefix(sType[t], "meta") {
-			for sc := range sInfo {
-				if sInfo[sc].Local {
-					go collectMetax(ns, sInfo[sc].Addr, proxyURL, c)
-					if sType[t] == "meta2" {
-						go collectMeta2Info(ns, sInfo[sc].Addr, proxyURL, c)
-					}
-				}
+		var addrs, all []string
+		for sc := range sInfo {
+			if sInfo[sc].Local {
+				addrs = append(addrs, sInfo[sc].Addr)
 			}
+			all = append(all, sInfo[sc].Addr)
 		}
+		if sType[t] == "oioproxy" && p.proxies != nil {
+			p.proxies.Discovered(all)
+		}
+		cacheLocalServices(ns, sType[t], addrs)
+		collectServices(ns, sType[t], addrs, p, true, c)
 	}
 }
 
-func serviceTypes(proxyURL string, ns string) (serviceType, error) {
-	url := fmt.Sprintf("http://%s/v3.0/%s/conscience/info?what=types", proxyURL, ns)
+// collectServices starts the collection of local services of a type. Without
+// proxy, only the services serving their own stats or status endpoint are
+// collected. listed reports whether the services were listed in the conscience
+// during the cycle, so that their service chart is sent
+func collectServices(ns string, sType string, addrs []string, p proxy, listed bool, c chan netdata.Metric) {
+	_, own := statEndpoints[sType]
+	_, status := statusEndpoints[sType]
+	if !own && !status && p.url == "" {
+		return
+	}
+	for _, addr := range addrs {
+		if requestTypes[sType] {
+			go collectService(ns, sType, addr, p, listed, c)
+		} else if strings.HasPrefix(sType, "meta") {
+			go collectMetax(ns, sType, addr, p, listed, c)
+			go collectMetaInfo(ns, sType, addr, p, c)
+		}
+	}
+}
+
+/*
+CollectCluster - collect namespace aggregates of the conscience per service
+type, regardless of the host the services run on
+*/
+func CollectCluster(proxyURL string, ns string, c chan netdata.Metric) {
+	p := proxy{url: proxyURL}
+	sType, err := serviceTypes(p, ns)
+	if err != nil {
+		logger.Warn("Could not retrieve service types", "ns", ns, "err", err)
+		return
+	}
+	collectCluster(p, ns, sType, c)
+}
+
+/*
+CollectClusterProxies - collect namespace aggregates through the first proxy
+of the namespace that answers, and report which one was used
+*/
+func CollectClusterProxies(proxies *Proxies, ns string, c chan netdata.Metric) {
+	proxyURL, err := proxies.Get()
+	if err == nil {
+		var sType serviceType
+		p := proxy{url: proxyURL, proxies: proxies}
+		if sType, err = serviceTypes(p, ns); err == nil {
+			proxies.report(c)
+			collectCluster(p, ns, sType, c)
+			return
+		}
+		proxies.Failed(proxyURL)
+	}
+	logger.Warn("Could not retrieve service types", "ns", ns, "err", err)
+}
+
+func collectCluster(p proxy, ns string, sType serviceType, c chan netdata.Metric) {
+	for _, t := range sType {
+		sInfo, err := listServices(p, ns, t)
+		if err != nil {
+			logger.Warn("Could not retrieve services", "ns", ns, "type", t, "err", err)
+			continue
+		}
+		clusterAggregates(ns, t, sInfo, c)
+	}
+}
+
+// clusterAggregates sends the number of services of a type, how many have a
+// zero score or are locked, and the min/avg/max score
+func clusterAggregates(ns string, sType string, sInfo serviceInfo, c chan netdata.Metric) {
+	zero, locked, sum := 0, 0, 0
+	min, max := 0, 0
+	for i, s := range sInfo {
+		if s.Score == 0 {
+			zero++
+		}
+		if s.Tags.Lock {
+			locked++
+		}
+		if i == 0 || s.Score < min {
+			min = s.Score
+		}
+		if i == 0 || s.Score > max {
+			max = s.Score
+		}
+		sum += s.Score
+	}
+	avg := 0
+	if len(sInfo) > 0 {
+		avg = (sum + len(sInfo)/2) / len(sInfo)
+	}
+	chart := fmt.Sprintf("cluster_%s_%s", ns, sType)
+	netdata.Update(chart+"_services", "count", fmt.Sprint(len(sInfo)), c)
+	netdata.Update(chart+"_services", "zero_score", fmt.Sprint(zero), c)
+	netdata.Update(chart+"_services", "locked", fmt.Sprint(locked), c)
+	netdata.Update(chart+"_score", "min", fmt.Sprint(min), c)
+	netdata.Update(chart+"_score", "avg", fmt.Sprint(avg), c)
+	netdata.Update(chart+"_score", "max", fmt.Sprint(max), c)
+}
+
+// CheckProxy verifies that the proxy answers for the namespace conscience
+func CheckProxy(proxyURL string, ns string) error {
+	sType, err := serviceTypes(proxy{url: proxyURL}, ns)
+	if err != nil {
+		return err
+	}
+	if len(sType) == 0 {
+		return fmt.Errorf("no service type registered in %s", ns)
+	}
+	return nil
+}
+
+func serviceTypes(p proxy, ns string) (serviceType, error) {
 	res := serviceType{}
 
-	typesResponse, err := util.HTTPGet(url)
+	typesResponse, err := p.get(fmt.Sprintf("/v3.0/%s/conscience/info?what=types", ns))
 	if err != nil {
 		return nil, err
 	}
@@ -163,25 +385,188 @@ func serviceTypes(proxyURL string, ns string) (serviceType, error) {
 	return res, nil
 }
 
-func collectRawx(ns string, service string, c chan netdata.Metric) {
-	url := fmt.Sprintf("http://%s/stat", service)
-	res, err := util.HTTPGet(url)
+// statEndpoints -- stats endpoints served by the services themselves, the
+// other types are reached through the proxy forward/stats API
+var statEndpoints = map[string]string{
+	"rawx":     "/stat",
+	"oioproxy": "/v3.0/status",
+}
+
+// statusEndpoints -- JSON status documents of the services whose counters are
+// only reached through the proxy forward/stats API. Their gauges and config
+// complete the forward stats, and are still read without proxy
+var statusEndpoints = map[string]string{
+	"rdir":    "/status",
+	"account": "/status",
+}
+
+// requestTypes -- service types whose request counters are charted per service
+var requestTypes = map[string]bool{"rawx": true, "rdir": true, "account": true, "oioproxy": true, "sqlx": true}
+
+func collectService(ns string, sType string, service string, p proxy, listed bool, c chan netdata.Metric) {
+	var res string
+	var err error
+	if endpoint, ok := statEndpoints[sType]; ok {
+		res, err = util.HTTPGet(util.URL(service, endpoint))
+	} else if p.url != "" {
+		res, err = p.get("/v3.0/forward/stats?id=" + service)
+	}
+	status, hasStatus := statusEndpoints[sType]
 	if err != nil {
-		log.Println("WARN: rawx metric collection failed", err)
-		return
+		logger.Warn("Service metric collection failed", "type", sType, "service", service, "err", err)
+		if !hasStatus {
+			return
+		}
+	}
+	stat := parseStat(res)
+	if hasStatus {
+		if res, err = util.HTTPGet(util.URL(service, status)); err != nil {
+			logger.Warn("Service status collection failed", "type", sType, "service", service, "err", err)
+		} else {
+			stat.merge(parseStat(res))
+		}
+	}
+	requestCharts(ns, sType, service, stat.counters, time.Now(), c)
+	statCharts(ns, sType, service, stat, listed, c)
+}
+
+// stat -- lines of a service stat endpoint, by kind
+type stat struct {
+	counters map[string]string
+	gauges   map[string]string
+	config   map[string]string
+}
+
+// merge adds the lines of another endpoint, without overriding those known
+func (st stat) merge(other stat) {
+	for _, kind := range []struct{ dst, src map[string]string }{
+		{st.counters, other.counters},
+		{st.gauges, other.gauges},
+		{st.config, other.config},
+	} {
+		for name, value := range kind.src {
+			if _, ok := kind.dst[name]; !ok {
+				kind.dst[name] = value
+			}
+		}
 	}
-	var lines = strings.Split(res, "\n")
-	for i := range lines {
-		s := strings.Split(lines[i], " ")
+}
+
+// parseStat reads "<kind> <name> <value>" lines, config values may contain
+// spaces. A JSON object gives gauges for its numbers, config for the others
+func parseStat(res string) stat {
+	st := stat{
+		counters: make(map[string]string),
+		gauges:   make(map[string]string),
+		config:   make(map[string]string),
+	}
+	if strings.HasPrefix(strings.TrimSpace(res), "{") {
+		fields := make(map[string]interface{})
+		if err := json.Unmarshal([]byte(res), &fields); err != nil {
+			return st
+		}
+		for name, value := range fields {
+			switch v := value.(type) {
+			case float64:
+				st.gauges[name] = strconv.FormatFloat(v, 'f', -1, 64)
+			case string:
+				st.config[name] = v
+			case bool:
+				st.config[name] = strconv.FormatBool(v)
+			}
+		}
+		return st
+	}
+	for _, line := range strings.Split(res, "\n") {
+		s := strings.Split(line, " ")
 		if len(s) < 3 {
 			continue
 		}
-		if s[0] == "counter" {
-			if diff := diffCounter(s[1], util.SID(service, ns), s[2]); diff != "" {
-				netdata.Update(s[1], util.SID(service, ns), diff, c)
+		switch s[0] {
+		case "counter":
+			st.counters[s[1]] = s[2]
+		case "gauge":
+			st.gauges[s[1]] = s[2]
+		case "config":
+			st.config[s[1]] = strings.Join(s[2:], " ")
+		}
+	}
+	return st
+}
+
+// statCharts sends the gauges of a service as absolute values, one
+// <type>_<gauge> chart per gauge. Its config entries become labels of the
+// service chart when labels is set, as that chart is only sent for the services
+// listed in the conscience
+func statCharts(ns string, sType string, service string, st stat, labels bool, c chan netdata.Metric) {
+	sid := util.SID(service, ns)
+	for name, value := range st.gauges {
+		v, ok := formatNumber(value)
+		if !ok {
+			continue
+		}
+		netdata.Update(sType+"_"+name, sid, v, c)
+	}
+	if labels {
+		chart := serviceChart(util.SID(sType+"_"+service, ns))
+		for name, value := range st.config {
+			netdata.Label(chart, name, value, c)
+		}
+	}
+	if volume, ok := st.config["volume"]; ok {
+		go volumeInfo(service, ns, volume, c)
+	}
+}
+
+var statusCode = regexp.MustCompile(`^[1-5][0-9][0-9]$`)
+
+// requestCharts sends the counters of a service as structured charts: requests
+// and average latency per method, responses per status class and per code, and
+// bytes read and written. Other counters get their own rate chart
+func requestCharts(ns string, sType string, service string, counters map[string]string, now time.Time, c chan netdata.Metric) {
+	sid := util.SID(service, ns)
+	prefix := sType + "_"
+	perSecond := make(map[string]float64)
+	for name, value := range counters {
+		if rate, ok := counterRate(name, sid, value, now); ok {
+			perSecond[name] = rate
+		}
+	}
+	for name, rate := range perSecond {
+		switch {
+		case name == "req.hits":
+			// Only used for the overall latency
+		case strings.HasPrefix(name, "req.hits."):
+			netdata.Update(prefix+"requests_"+sid, strings.TrimPrefix(name, "req.hits."), round(rate), c)
+		case name == "req.time" || strings.HasPrefix(name, "req.time."):
+			method := strings.TrimPrefix(strings.TrimPrefix(name, "req.time"), ".")
+			hits, ok := perSecond["req.hits"+strings.TrimPrefix(name, "req.time")]
+			if !ok {
+				continue
+			}
+			if method == "" {
+				method = "all"
+			}
+			// Both rates cover the same interval, their ratio is the time
+			// spent per request
+			latency := 0.0
+			if hits > 0 {
+				latency = rate / hits
 			}
-		} else if s[1] == "volume" {
-			go volumeInfo(service, ns, s[2], c)
+			netdata.Update(prefix+"latency_"+sid, method, round(latency), c)
+		case strings.HasPrefix(name, "rep.hits."):
+			status := strings.TrimPrefix(name, "rep.hits.")
+			if statusCode.MatchString(status) {
+				netdata.Update(prefix+"codes_"+sid, status, round(rate), c)
+			} else {
+				netdata.Update(prefix+"responses_"+sid, status, round(rate), c)
+			}
+		case name == "rep.bread":
+			netdata.Update(prefix+"bytes_"+sid, "read", round(rate), c)
+		case name == "rep.bwritten":
+			netdata.Update(prefix+"bytes_"+sid, "written", round(rate), c)
+		default:
+			netdata.Update(name, sid, round(rate), c)
 		}
 	}
 }
@@ -189,74 +574,153 @@ func collectRawx(ns string, service string, c chan netdata.Metric) {
 /*
 CollectMetax - update metrics for M0/M1/M2 servicess
 */
-func collectMetax(ns string, service string, proxyURL string, c chan netdata.Metric) {
-	url := fmt.Sprintf("http://%s/v3.0/forward/stats?id=%s", proxyURL, service)
-	res, err := util.HTTPGet(url)
+func collectMetax(ns string, sType string, service string, p proxy, listed bool, c chan netdata.Metric) {
+	res, err := p.get("/v3.0/forward/stats?id=" + service)
 	if err != nil {
-		log.Println("WARN: metaX stats collection failed", err)
+		logger.Warn("MetaX stats collection failed", "service", service, "err", err)
 		return
 	}
-	var lines = strings.Split(res, "\n")
-	for i := range lines {
-		s := strings.Split(lines[i], " ")
-		if len(s) < 3 {
-			continue
-		}
-		if s[0] == "counter" {
-			if diff := diffCounter(s[1], util.SID(service, ns), s[2]); diff != "" {
-				netdata.Update(s[1], util.SID(service, ns), diff, c)
-			}
-		} else if s[1] == "volume" {
-			go volumeInfo(service, ns, s[2], c)
+	now := time.Now()
+	stat := parseStat(res)
+	for name, value := range stat.counters {
+		if diff := diffCounter(name, util.SID(service, ns), value, now); diff != "" {
+			netdata.Update(name, util.SID(service, ns), diff, c)
 		}
 	}
+	statCharts(ns, sType, service, stat, listed, c)
 }
 
-type metaxInfoBody struct {
-	Cache     map[string]int64
-	Elections map[string]int64
+// infoSections -- chart name of the info sections charted before fields were
+// discovered, kept for compatibility
+var infoSections = map[string]string{
+	"cache": "cache_bases",
 }
 
-func collectMeta2Info(ns, service, proxyURL string, c chan netdata.Metric) {
-	url := fmt.Sprintf("http://%s/v3.0/forward/info?id=%s", proxyURL, service)
-	sid := util.SID(service, ns)
-	info := metaxInfoBody{}
+func collectMetaInfo(ns string, sType string, service string, p proxy, c chan netdata.Metric) {
+	info := make(map[string]interface{})
 
-	res, err := util.HTTPGet(url)
+	res, err := p.get("/v3.0/forward/info?id=" + service)
 
 	if err != nil {
-		log.Println("WARN: meta2 info collection failed", err)
+		logger.Warn("MetaX info collection failed", "service", service, "err", err)
 		return
 	}
 
-	if err = json.Unmarshal([]byte(res), &info); err != nil {
-		log.Println("WARN: meta2 info collection failed", err)
+	// Numbers are kept as text, so that large integers stay exact
+	decoder := json.NewDecoder(strings.NewReader(res))
+	decoder.UseNumber()
+	if err = decoder.Decode(&info); err != nil {
+		logger.Warn("MetaX info collection failed", "service", service, "err", err)
 		return
 	}
 
-	for dim, val := range info.Cache {
-		netdata.Update(fmt.Sprintf("meta2_cache_bases_%s", dim), sid, fmt.Sprint(val), c)
+	metaInfoCharts(ns, sType, service, info, c)
+}
+
+// metaInfoCharts sends every numeric field of the info document, such as the
+// cache, elections, thread pool and sqlite sections. Each field gets its own
+// chart named after the type, section and field, with a dimension per service
+func metaInfoCharts(ns string, sType string, service string, info map[string]interface{}, c chan netdata.Metric) {
+	sid := util.SID(service, ns)
+	for section, value := range info {
+		name := strings.ToLower(section)
+		if compat, ok := infoSections[name]; ok {
+			name = compat
+		}
+		for field, v := range infoFields(name, value) {
+			netdata.Update(fmt.Sprintf("%s_%s", sType, field), sid, v, c)
+		}
 	}
-	for dim, val := range info.Elections {
-		netdata.Update(fmt.Sprintf("meta2_elections_%s", dim), sid, fmt.Sprint(val), c)
+}
+
+// infoFields flattens the numbers of an info section, nested keys are joined
+// with underscores
+func infoFields(prefix string, value interface{}) map[string]string {
+	fields := make(map[string]string)
+	switch v := value.(type) {
+	case json.Number:
+		if f, ok := formatNumber(v.String()); ok {
+			fields[prefix] = f
+		}
+	case float64:
+		fields[prefix] = round(v)
+	case map[string]interface{}:
+		for key, nested := range v {
+			for field, f := range infoFields(prefix+"_"+key, nested) {
+				fields[field] = f
+			}
+		}
 	}
+	return fields
 }
 
 func volumeInfo(service string, ns string, volume string, c chan netdata.Metric) {
 	info, fsid, err := util.VolumeInfo(volume)
 	if err != nil {
-		log.Println("WARN: volume info collection failed", err)
+		logger.Warn("Volume info collection failed", "service", service, "volume", volume, "err", err)
 		return
 	}
 	for dim, val := range info {
 		netdata.Update(dim, util.SID(service, ns, fsid), fmt.Sprint(val), c)
 	}
+	disk, err := util.VolumeDisk(volume)
+	if err != nil {
+		logger.Warn("Disk stats collection failed", "service", service, "volume", volume, "err", err)
+		return
+	}
+	diskCharts(ns, service, disk, time.Now(), c)
+}
+
+// diskCharts sends the IO rates, latency, queue depth and utilisation of the
+// block device backing the volume of a service
+func diskCharts(ns string, service string, disk util.DiskStats, now time.Time, c chan netdata.Metric) {
+	sid := util.SID(service, ns)
+	counters := map[string]uint64{
+		"reads":         disk.Reads,
+		"writes":        disk.Writes,
+		"read_sectors":  disk.ReadSectors,
+		"write_sectors": disk.WriteSectors,
+		"read_time":     disk.ReadTime,
+		"write_time":    disk.WriteTime,
+		"io_time":       disk.IOTime,
+		"weighted_time": disk.WeightedTime,
+	}
+	perSecond := make(map[string]float64)
+	for name, value := range counters {
+		if rate, ok := counterRate("disk."+name+"."+disk.Device, sid, fmt.Sprint(value), now); ok {
+			perSecond[name] = rate
+		}
+	}
+	for _, chart := range []string{"disk_ops_", "disk_bytes_", "disk_latency_", "disk_queue_", "disk_util_"} {
+		netdata.Label(chart+sid, "device", disk.Device, c)
+	}
+	netdata.Update("disk_queue_"+sid, "in_flight", fmt.Sprint(disk.InFlight), c)
+	if len(perSecond) != len(counters) {
+		// Rates start with the second sample
+		return
+	}
+	netdata.Update("disk_ops_"+sid, "read", round(perSecond["reads"]), c)
+	netdata.Update("disk_ops_"+sid, "write", round(perSecond["writes"]), c)
+	netdata.Update("disk_bytes_"+sid, "read", round(perSecond["read_sectors"]*util.SectorSize), c)
+	netdata.Update("disk_bytes_"+sid, "written", round(perSecond["write_sectors"]*util.SectorSize), c)
+	// Milliseconds spent per operation, in microseconds
+	latency := func(ms, ops float64) string {
+		if ops == 0 {
+			return "0"
+		}
+		return round(1000 * ms / ops)
+	}
+	netdata.Update("disk_latency_"+sid, "read", latency(perSecond["read_time"], perSecond["reads"]), c)
+	netdata.Update("disk_latency_"+sid, "write", latency(perSecond["write_time"], perSecond["writes"]), c)
+	// Milliseconds of IO per second of the interval give the average queue
+	// depth and the busy percentage
+	netdata.Update("disk_queue_"+sid, "avg", round(perSecond["weighted_time"]/1000), c)
+	netdata.Update("disk_util_"+sid, "busy", round(perSecond["io_time"]/10), c)
 }
 
-func collectScore(proxyURL string, ns string, sType string, c chan netdata.Metric) (serviceInfo, error) {
+func listServices(p proxy, ns string, sType string) (serviceInfo, error) {
 	sInfo := serviceInfo{}
-	url := fmt.Sprintf("http://%s/v3.0/%s/conscience/list?type=%s", proxyURL, ns, sType)
-	res, err := util.HTTPGet(url)
+	res, err := p.get(fmt.Sprintf("/v3.0/%s/conscience/list?type=%s", ns, sType))
 	if err != nil {
 		return nil, err
 	}
@@ -264,13 +728,74 @@ func collectScore(proxyURL string, ns string, sType string, c chan netdata.Metri
 	if err != nil {
 		return nil, err
 	}
+	return sInfo, nil
+}
+
+func collectScore(p proxy, ns string, sType string, c chan netdata.Metric) (serviceInfo, error) {
+	sInfo, err := listServices(p, ns, sType)
+	if err != nil {
+		return nil, err
+	}
+	up, locked := 0, 0
 	for i := range sInfo {
+		tags := sInfo[i].Tags
+		if tags.Up {
+			up++
+		}
+		if tags.Lock {
+			locked++
+		}
 		if util.IsSameHost(sInfo[i].Addr) {
 			sInfo[i].Local = true
-			netdata.Update("score", util.SID(sType+"_"+sInfo[i].Addr, ns), fmt.Sprint(sInfo[i].Score), c)
+			sid := util.SID(sType+"_"+sInfo[i].Addr, ns)
+			netdata.Update("score", sid, fmt.Sprint(sInfo[i].Score), c)
+			serviceStats(sid, tags, c)
+			serviceStatus(sid, sType, sInfo[i].Addr, tags, c)
 		} else {
 			sInfo[i].Local = false
 		}
 	}
+	serviceCounts(ns, sType, len(sInfo), up, locked, c)
 	return sInfo, nil
 }
+
+// serviceCounts sends the number of up, down and locked services of a type
+func serviceCounts(ns string, sType string, total int, up int, locked int, c chan netdata.Metric) {
+	chart := fmt.Sprintf("services_%s_%s", ns, sType)
+	netdata.Update(chart, "up", fmt.Sprint(up), c)
+	netdata.Update(chart, "down", fmt.Sprint(total-up), c)
+	netdata.Update(chart, "locked", fmt.Sprint(locked), c)
+}
+
+// serviceStatus sends whether a local service is up and locked, along with its
+// location, slots and volume as labels
+func serviceStatus(sid string, sType string, addr string, tags serviceTags, c chan netdata.Metric) {
+	chart := serviceChart(sid)
+	netdata.Label(chart, "type", sType, c)
+	netdata.Label(chart, "addr", addr, c)
+	for name, value := range map[string]string{"loc": tags.Loc, "slots": tags.Slots, "vol": tags.Vol} {
+		if value != "" {
+			netdata.Label(chart, name, value, c)
+		}
+	}
+	netdata.Update(chart, "up", boolValue(tags.Up), c)
+	netdata.Update(chart, "locked", boolValue(tags.Lock), c)
+}
+
+// serviceStats sends the stat tags of a service, from which its score is computed
+func serviceStats(sid string, tags serviceTags, c chan netdata.Metric) {
+	for name, value := range tags.Stats {
+		netdata.Update("stat_"+strings.Replace(name, ".", "_", -1), sid, round(value), c)
+	}
+}
+
+func serviceChart(sid string) string {
+	return "service_" + sid
+}
+
+func boolValue(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
@@ -17,11 +17,18 @@
 package openio
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"net/http"
+	"net/http/httptest"
 	"oionetdata/netdata"
+	"oionetdata/util"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -104,3 +111,578 @@ func TestOpenIOCollector(t *testing.T) {
 	//     // buf[m.Chart] = append(buf[m.Chart], fmt.Sprintf("SET %s %s\n", m.Dim, m.Value)...)
 	// }
 }
+
+func TestCollectScore(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, "./testdata/types_rawx.json")
+	}))
+	defer srv.Close()
+
+	c := make(chan netdata.Metric, 100)
+	sInfo, err := collectScore(proxy{url: strings.TrimPrefix(srv.URL, "http://")}, "OPENIO", "rawx", c)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(sInfo) != 1 || !sInfo[0].Tags.Lock || sInfo[0].Tags.Loc != "server.1" {
+		t.Fatalf("unexpected services %+v", sInfo)
+	}
+	close(c)
+
+	got := make(map[string]string)
+	for m := range c {
+		key := m.Chart + " " + m.Dim
+		if m.Label {
+			key += " label"
+		}
+		got[key] = m.Value
+	}
+	service := "openio.service_OPENIO_rawx_127_0_0_1_6006"
+	expected := map[string]string{
+		"openio.score OPENIO.rawx_127_0_0_1_6006":      "78",
+		service + " type label":                        "rawx",
+		service + " addr label":                        "127.0.0.1:6006",
+		service + " loc label":                         "server.1",
+		service + " slots label":                       "rawx",
+		service + " vol label":                         "/mnt/hdd1/OPENIO/rawx-1",
+		service + " up":                                "1",
+		service + " locked":                            "1",
+		"openio.stat_space OPENIO.rawx_127_0_0_1_6006": "81",
+		"openio.stat_cpu OPENIO.rawx_127_0_0_1_6006":   "98",
+		"openio.stat_io OPENIO.rawx_127_0_0_1_6006":    "78",
+		"openio.services_OPENIO_rawx up":               "1",
+		"openio.services_OPENIO_rawx down":             "0",
+		"openio.services_OPENIO_rawx locked":           "1",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("unexpected metrics got\n%v\nexpected\n%v", got, expected)
+	}
+}
+
+func TestClusterAggregates(t *testing.T) {
+	sInfo := serviceInfo{}
+	if err := json.Unmarshal([]byte(`[
+		{"addr": "10.0.0.1:6200", "score": 80, "tags": {"tag.up": true}},
+		{"addr": "10.0.0.2:6200", "score": 0, "tags": {"tag.up": false, "tag.lock": true}},
+		{"addr": "10.0.0.3:6200", "score": 45, "tags": {"tag.up": true, "tag.lock": true}}
+	]`), &sInfo); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	c := make(chan netdata.Metric, 10)
+	clusterAggregates("OPENIO", "rawx", sInfo, c)
+	close(c)
+	got := make(map[string]string)
+	for m := range c {
+		got[m.Chart+" "+m.Dim] = m.Value
+	}
+	expected := map[string]string{
+		"openio.cluster_OPENIO_rawx_services count":      "3",
+		"openio.cluster_OPENIO_rawx_services zero_score": "1",
+		"openio.cluster_OPENIO_rawx_services locked":     "2",
+		"openio.cluster_OPENIO_rawx_score min":           "0",
+		"openio.cluster_OPENIO_rawx_score avg":           "42",
+		"openio.cluster_OPENIO_rawx_score max":           "80",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("unexpected metrics got\n%v\nexpected\n%v", got, expected)
+	}
+}
+
+func TestRequestCharts(t *testing.T) {
+	c := make(chan netdata.Metric, 100)
+	now := time.Now()
+	requestCharts("OPENIO", "rawx", "127.0.0.1:6201", map[string]string{
+		"req.hits": "100", "req.time": "10000",
+		"req.hits.get": "60", "req.time.get": "3000",
+		"req.hits.put": "40", "req.time.put": "7000",
+		"req.hits.del": "5", "req.time.del": "500",
+		"rep.hits.2xx": "90", "rep.hits.404": "10",
+		"rep.bread": "1000", "rep.bwritten": "0",
+		"rep.other": "1",
+	}, now, c)
+	if len(c) != 0 {
+		t.Fatalf("unexpected metrics on the first sample")
+	}
+	requestCharts("OPENIO", "rawx", "127.0.0.1:6201", map[string]string{
+		"req.hits": "200", "req.time": "40000",
+		"req.hits.get": "130", "req.time.get": "10000",
+		"req.hits.put": "70", "req.time.put": "30000",
+		"req.hits.del": "5", "req.time.del": "500",
+		"rep.hits.2xx": "170", "rep.hits.404": "30",
+		"rep.bread": "51000", "rep.bwritten": "20000",
+		"rep.other": "11",
+	}, now.Add(10*time.Second), c)
+	close(c)
+
+	got := make(map[string]string)
+	for m := range c {
+		got[m.Chart+" "+m.Dim] = m.Value
+	}
+	sid := "OPENIO_127_0_0_1_6201"
+	expected := map[string]string{
+		"openio.rawx_requests_" + sid + " get":   "7",
+		"openio.rawx_requests_" + sid + " put":   "3",
+		"openio.rawx_requests_" + sid + " del":   "0",
+		"openio.rawx_latency_" + sid + " all":    "300",
+		"openio.rawx_latency_" + sid + " get":    "100",
+		"openio.rawx_latency_" + sid + " put":    "767",
+		"openio.rawx_latency_" + sid + " del":    "0",
+		"openio.rawx_responses_" + sid + " 2xx":  "8",
+		"openio.rawx_codes_" + sid + " 404":      "2",
+		"openio.rawx_bytes_" + sid + " read":     "5000",
+		"openio.rawx_bytes_" + sid + " written":  "2000",
+		"openio.rep_other OPENIO.127_0_0_1_6201": "1",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("unexpected metrics got\n%v\nexpected\n%v", got, expected)
+	}
+}
+
+func TestStatCharts(t *testing.T) {
+	st := parseStat("counter req.hits 12\ngauge cnx.client 7\nconfig service_id 127.0.0.1:6120\nconfig version 4.2.0 (abc)\ninvalid\n")
+	if len(st.counters) != 1 || st.config["version"] != "4.2.0 (abc)" {
+		t.Fatalf("unexpected stat %+v", st)
+	}
+
+	// Without the service chart in the cycle, only the gauges are sent
+	c := make(chan netdata.Metric, 10)
+	statCharts("OPENIO", "meta2", "127.0.0.1:6120", st, false, c)
+	if len(c) != 1 {
+		t.Fatalf("expected only the gauge, got %d metrics", len(c))
+	}
+	<-c
+	statCharts("OPENIO", "meta2", "127.0.0.1:6120", st, true, c)
+	close(c)
+	got := make(map[string]string)
+	for m := range c {
+		key := m.Chart + " " + m.Dim
+		if m.Label {
+			key += " label"
+		}
+		got[key] = m.Value
+	}
+	service := "openio.service_OPENIO_meta2_127_0_0_1_6120"
+	expected := map[string]string{
+		"openio.meta2_cnx_client OPENIO.127_0_0_1_6120": "7",
+		service + " service_id label":                   "127.0.0.1:6120",
+		service + " version label":                      "4.2.0 (abc)",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("unexpected metrics got\n%v\nexpected\n%v", got, expected)
+	}
+}
+
+func TestCollectService(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/status":
+			fmt.Fprint(w, `{"opened_db_count": 3, "status": "ok"}`)
+		case "/v3.0/forward/stats":
+			fmt.Fprintf(w, "counter req.hits.get 10\ngauge cnx.client 2\nconfig service_id %s\n", r.URL.Query().Get("id"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	addr := strings.TrimPrefix(srv.URL, "http://")
+	sid := util.SID(addr, "OPENIO")
+	account := "openio.service_" + strings.Replace(util.SID("account_"+addr, "OPENIO"), ".", "_", -1)
+	requests := "openio.account_requests_" + strings.Replace(sid, ".", "_", -1)
+
+	// The account counters were sampled 10s ago
+	rates = util.NewRates()
+	counterRate("req.hits.get", sid, "0", time.Now().Add(-10*time.Second))
+
+	// Without proxy, rdir only reports the gauges of its /status document.
+	// Through the proxy, the account forward stats are completed by its
+	// /status document
+	c := make(chan netdata.Metric, 20)
+	collectService("OPENIO", "rdir", addr, proxy{}, false, c)
+	collectService("OPENIO", "account", addr, proxy{url: addr}, true, c)
+	collectService("OPENIO", "sqlx", "10.0.0.1:6130", proxy{url: addr}, true, c)
+	close(c)
+	got := make(map[string]string)
+	for m := range c {
+		key := m.Chart + " " + m.Dim
+		if m.Label {
+			key += " label"
+		}
+		got[key] = m.Value
+	}
+	expected := map[string]string{
+		"openio.rdir_opened_db_count " + sid:                        "3",
+		"openio.account_opened_db_count " + sid:                     "3",
+		requests + " get":                                           "1",
+		"openio.account_cnx_client " + sid:                          "2",
+		account + " service_id label":                               addr,
+		account + " status label":                                   "ok",
+		"openio.sqlx_cnx_client OPENIO.10_0_0_1_6130":               "2",
+		"openio.service_OPENIO_sqlx_10_0_0_1_6130 service_id label": "10.0.0.1:6130",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("unexpected metrics got\n%v\nexpected\n%v", got, expected)
+	}
+}
+
+func TestMetaInfoCharts(t *testing.T) {
+	info := make(map[string]interface{})
+	if err := json.Unmarshal([]byte(`{
+		"Cache": {"hot": 12, "max": 100},
+		"elections": {"master": 3},
+		"threads": {"pool": {"active": 2, "idle": 6}},
+		"sqlite": {"open": 4, "path": "/var/lib/oio"},
+		"uptime": 3600,
+		"version": "4.2.0"
+	}`), &info); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	c := make(chan netdata.Metric, 20)
+	metaInfoCharts("OPENIO", "meta1", "127.0.0.1:6110", info, c)
+	close(c)
+	got := make(map[string]string)
+	for m := range c {
+		got[m.Chart+" "+m.Dim] = m.Value
+	}
+	sid := "OPENIO.127_0_0_1_6110"
+	expected := map[string]string{
+		"openio.meta1_cache_bases_hot " + sid:     "12",
+		"openio.meta1_cache_bases_max " + sid:     "100",
+		"openio.meta1_elections_master " + sid:    "3",
+		"openio.meta1_threads_pool_active " + sid: "2",
+		"openio.meta1_threads_pool_idle " + sid:   "6",
+		"openio.meta1_sqlite_open " + sid:         "4",
+		"openio.meta1_uptime " + sid:              "3600",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("unexpected metrics got\n%v\nexpected\n%v", got, expected)
+	}
+}
+
+func TestDiscoverServices(t *testing.T) {
+	services, err := discoverServices("./testdata/sds", "OPENIO")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string][]string{
+		"rawx":  {"127.0.0.1:6200"},
+		"meta2": {"127.0.0.1:6120"},
+	}
+	if !reflect.DeepEqual(services, expected) {
+		t.Fatalf("unexpected services got %v expected %v", services, expected)
+	}
+}
+
+func TestCollectFallback(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, "./testdata/stat_rawx")
+	}))
+	defer srv.Close()
+	addr := strings.TrimPrefix(srv.URL, "http://")
+	cacheLocalServices("FALLBACK", "rawx", []string{addr})
+
+	// The stat requests were sampled 10s ago
+	sid := util.SID(addr, "FALLBACK")
+	rates = util.NewRates()
+	counterRate("req.hits.stat", sid, "0", time.Now().Add(-10*time.Second))
+
+	// The conscience is unreachable, the cached rawx is still collected. Its
+	// service chart is not sent, neither are its labels
+	c := make(chan netdata.Metric, 100)
+	Collect("127.0.0.1:1", "FALLBACK", c)
+	got := make(map[string]string)
+	for _, m := range drain(c) {
+		got[m.Chart+" "+m.Dim] = m.Value
+	}
+	expected := map[string]string{
+		"openio.rawx_requests_" + strings.Replace(sid, ".", "_", -1) + " stat": "3",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected %v, got %v", expected, got)
+	}
+}
+
+// drain returns the metrics sent by collection goroutines, until none was sent
+// for a while
+func drain(c chan netdata.Metric) []netdata.Metric {
+	var metrics []netdata.Metric
+	for {
+		select {
+		case m := <-c:
+			metrics = append(metrics, m)
+		case <-time.After(500 * time.Millisecond):
+			return metrics
+		}
+	}
+}
+
+func TestProxiesFailover(t *testing.T) {
+	down := map[string]bool{"a:1": true}
+	checks := 0
+	now := time.Unix(1000, 0)
+	p := NewProxies("OPENIO", []string{"a:1", "b:1"})
+	p.now = func() time.Time { return now }
+	p.check = func(proxyURL string, ns string) error {
+		checks++
+		if down[proxyURL] {
+			return fmt.Errorf("%s down", proxyURL)
+		}
+		return nil
+	}
+
+	if addr, err := p.Get(); err != nil || addr != "b:1" {
+		t.Fatalf("expected b:1, got %q %v", addr, err)
+	}
+
+	// The current proxy fails, the other one is not checked again before
+	// the retry delay
+	down["b:1"] = true
+	p.Failed("b:1")
+	checks = 0
+	if addr, err := p.Get(); err == nil || checks != 0 {
+		t.Fatalf("expected an error without checks, got %q after %d checks", addr, checks)
+	}
+
+	// A proxy listed in the conscience takes over
+	p.Discovered([]string{"b:1", "c:1"})
+	if addr, err := p.Get(); err != nil || addr != "c:1" {
+		t.Fatalf("expected c:1, got %q %v", addr, err)
+	}
+
+	// The preferred proxy is used again once it recovers and its retry
+	// delay expired
+	down["a:1"] = false
+	if addr, err := p.Get(); err != nil || addr != "c:1" {
+		t.Fatalf("expected c:1 during the retry delay, got %q %v", addr, err)
+	}
+	now = now.Add(proxyRetryDelay)
+	if addr, err := p.Get(); err != nil || addr != "a:1" {
+		t.Fatalf("expected a:1, got %q %v", addr, err)
+	}
+
+	c := make(chan netdata.Metric, 10)
+	p.report(c)
+	close(c)
+	got := make(map[string]string)
+	for m := range c {
+		got[m.Dim] = m.Value
+	}
+	expected := map[string]string{"index": "0", "candidates": "3", "addr": "a:1"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected %v, got %v", expected, got)
+	}
+}
+
+// proxyServer answers for the OPENIO namespace, and drops the connection of
+// the requests whose path contains broken
+func proxyServer(broken string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if broken != "" && strings.Contains(r.URL.Path, broken) {
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+			return
+		}
+		switch r.URL.Path {
+		case "/v3.0/OPENIO/conscience/info":
+			fmt.Fprint(w, `["rawx","meta2"]`)
+		case "/v3.0/OPENIO/conscience/list":
+			http.ServeFile(w, r, "./testdata/types_rawx.json")
+		case "/v3.0/forward/stats":
+			fmt.Fprint(w, "gauge cnx.client 3\n")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func TestProxyCallFailover(t *testing.T) {
+	for _, broken := range []string{"/conscience/list", "/forward/stats"} {
+		a, b := proxyServer(broken), proxyServer("")
+		addrA, addrB := strings.TrimPrefix(a.URL, "http://"), strings.TrimPrefix(b.URL, "http://")
+		proxies := NewProxies("OPENIO", []string{addrA, addrB})
+
+		// The first proxy answers for the conscience, it is selected
+		if addr, err := proxies.Get(); err != nil || addr != addrA {
+			t.Fatalf("expected %s, got %q %v", addrA, addr, err)
+		}
+		p := proxy{url: addrA, proxies: proxies}
+		c := make(chan netdata.Metric, 100)
+		if broken == "/conscience/list" {
+			if sInfo, err := collectScore(p, "OPENIO", "rawx", c); err != nil || len(sInfo) != 1 {
+				t.Fatalf("%s: expected the services through %s, got %v %v", broken, addrB, sInfo, err)
+			}
+		} else {
+			collectMetax("OPENIO", "meta2", "10.0.0.1:6120", p, true, c)
+			close(c)
+			got := make(map[string]string)
+			for m := range c {
+				got[m.Chart] = m.Value
+			}
+			if len(got) != 1 || got["openio.meta2_cnx_client"] != "3" {
+				t.Fatalf("%s: expected the stats through %s, got %v", broken, addrB, got)
+			}
+		}
+
+		// The next calls go through the proxy that took over
+		if addr, ok := proxies.inUse(); !ok || addr != addrB {
+			t.Fatalf("%s: expected %s in use, got %q", broken, addrB, addr)
+		}
+		a.Close()
+		b.Close()
+	}
+}
+
+func TestProxyAddrs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "openio")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	conf := "[OPENIO]\nproxy=127.0.0.1:6006, 127.0.0.2:6006\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, "OPENIO"), []byte(conf), 0644); err != nil {
+		t.Fatal(err)
+	}
+	addrs, err := ProxyAddrs(dir, "OPENIO")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(addrs, []string{"127.0.0.1:6006", "127.0.0.2:6006"}) {
+		t.Fatalf("unexpected addresses %v", addrs)
+	}
+}
+
+func TestDiskCharts(t *testing.T) {
+	c := make(chan netdata.Metric, 100)
+	now := time.Now()
+	disk := util.DiskStats{
+		Device: "sdb", Reads: 1000, ReadSectors: 64000, ReadTime: 4000,
+		Writes: 500, WriteSectors: 32000, WriteTime: 6000,
+		InFlight: 2, IOTime: 3000, WeightedTime: 10000,
+	}
+	diskCharts("OPENIO", "127.0.0.1:6202", disk, now, c)
+	disk.Reads, disk.ReadSectors, disk.ReadTime = 2000, 84000, 9000
+	disk.Writes, disk.WriteSectors, disk.WriteTime = 500, 32000, 6000
+	disk.InFlight, disk.IOTime, disk.WeightedTime = 4, 8000, 30000
+	diskCharts("OPENIO", "127.0.0.1:6202", disk, now.Add(10*time.Second), c)
+	close(c)
+
+	got := make(map[string]string)
+	for m := range c {
+		key := m.Chart + " " + m.Dim
+		if m.Label {
+			key += " label"
+		}
+		got[key] = m.Value
+	}
+	sid := "OPENIO_127_0_0_1_6202"
+	expected := map[string]string{
+		"openio.disk_ops_" + sid + " device label":     "sdb",
+		"openio.disk_bytes_" + sid + " device label":   "sdb",
+		"openio.disk_latency_" + sid + " device label": "sdb",
+		"openio.disk_queue_" + sid + " device label":   "sdb",
+		"openio.disk_util_" + sid + " device label":    "sdb",
+		"openio.disk_ops_" + sid + " read":             "100",
+		"openio.disk_ops_" + sid + " write":            "0",
+		"openio.disk_bytes_" + sid + " read":           "1024000",
+		"openio.disk_bytes_" + sid + " written":        "0",
+		"openio.disk_latency_" + sid + " read":         "5000",
+		"openio.disk_latency_" + sid + " write":        "0",
+		"openio.disk_queue_" + sid + " in_flight":      "4",
+		"openio.disk_queue_" + sid + " avg":            "2",
+		"openio.disk_util_" + sid + " busy":            "50",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("unexpected metrics got\n%v\nexpected\n%v", got, expected)
+	}
+}
+
+func TestStateServices(t *testing.T) {
+	dir, err := ioutil.TempDir("", "openio")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.Setenv("NETDATA_CACHE_DIR", dir)
+	defer os.Unsetenv("NETDATA_CACHE_DIR")
+
+	cacheLocalServices("STATE", "rawx", []string{"127.0.0.1:6200"})
+	s := NewState(util.NewStateStore("openio.test", time.Minute))
+	if err := s.Save(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The cache is lost when the plugin reloads, and restored from the state
+	localServices.Lock()
+	delete(localServices.ns, "STATE")
+	localServices.Unlock()
+	if err := s.Load(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	services := fallbackServices("STATE")
+	if !reflect.DeepEqual(services, map[string][]string{"rawx": {"127.0.0.1:6200"}}) {
+		t.Fatalf("unexpected services %v", services)
+	}
+
+	// The caller gets a copy of the cache
+	services["rawx"][0] = "changed"
+	if addrs, _ := cachedLocalServices("STATE", "rawx"); addrs[0] != "127.0.0.1:6200" {
+		t.Fatalf("cache modified through fallbackServices: %v", addrs)
+	}
+}
+
+func TestServiceStats(t *testing.T) {
+	tags := serviceTags{}
+	in := `{"tag.up": true, "stat.space": 81.4, "stat.cpu": 98, "stat.req.idle": 3, "stat.name": "x"}`
+	if err := json.Unmarshal([]byte(in), &tags); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !tags.Up {
+		t.Fatalf("known tags not decoded: %+v", tags)
+	}
+
+	c := make(chan netdata.Metric, 10)
+	serviceStats("OPENIO.rawx_127_0_0_1_6200", tags, c)
+	close(c)
+	got := make(map[string]string)
+	for m := range c {
+		got[m.Chart+" "+m.Dim] = m.Value
+	}
+	expected := map[string]string{
+		"openio.stat_space OPENIO.rawx_127_0_0_1_6200":    "81",
+		"openio.stat_cpu OPENIO.rawx_127_0_0_1_6200":      "98",
+		"openio.stat_req_idle OPENIO.rawx_127_0_0_1_6200": "3",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("unexpected metrics got\n%v\nexpected\n%v", got, expected)
+	}
+}
+
+func TestRound(t *testing.T) {
+	for value, expected := range map[float64]string{
+		1.5:              "2",
+		1.4:              "1",
+		-1.7:             "-2",
+		-0.2:             "0",
+		float64(1 << 60): "1152921504606846976",
+	} {
+		if got := round(value); got != expected {
+			t.Errorf("round(%v): expected %s, got %s", value, expected, got)
+		}
+	}
+
+	// Integers over 2^53 are not altered through float64
+	info := make(map[string]interface{})
+	decoder := json.NewDecoder(strings.NewReader(`{"cache": {"bytes": 9007199254740993, "ratio": -1.7}}`))
+	decoder.UseNumber()
+	if err := decoder.Decode(&info); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	fields := infoFields("cache", info["cache"])
+	expected := map[string]string{"cache_bytes": "9007199254740993", "cache_ratio": "-2"}
+	if !reflect.DeepEqual(fields, expected) {
+		t.Fatalf("expected %v, got %v", expected, fields)
+	}
+	if v, ok := formatNumber("9007199254740993"); !ok || v != "9007199254740993" {
+		t.Fatalf("unexpected gauge %q", v)
+	}
+}
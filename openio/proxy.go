@@ -0,0 +1,180 @@
+// OpenIO netdata collectors
+// Copyright (C) 2019 OpenIO SAS
+//
+// This library is free software; you can redistribute it and/or
+// modify it under the terms of the GNU Lesser General Public
+// License as published by the Free Software Foundation; either
+// version 3.0 of the License, or (at your option) any later version.
+//
+// This library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package openio
+
+import (
+	"fmt"
+	"oionetdata/logger"
+	"oionetdata/netdata"
+	"oionetdata/util"
+	"strings"
+	"sync"
+	"time"
+)
+
+// proxyRetryDelay -- minimum delay before a proxy that failed is checked again,
+// so that an unreachable proxy does not stall every collection
+var proxyRetryDelay = time.Minute
+
+// Proxies -- proxy endpoints of a namespace. The configured endpoints are
+// tried first, in order, then those listed in the conscience; the first one
+// answering for the conscience serves the conscience and forward calls
+type Proxies struct {
+	sync.Mutex
+	ns         string
+	configured []string
+	discovered []string
+	current    string
+	failed     bool
+	retry      map[string]time.Time
+	now        func() time.Time
+
+	// check reports whether a proxy answers for the namespace
+	check func(proxyURL string, ns string) error
+}
+
+// NewProxies returns the proxies of a namespace, in order of preference
+func NewProxies(ns string, addrs []string) *Proxies {
+	return &Proxies{
+		ns:         ns,
+		configured: addrs,
+		retry:      make(map[string]time.Time),
+		now:        time.Now,
+		check:      CheckProxy,
+	}
+}
+
+// candidates returns the configured then discovered endpoints, without duplicates
+func (p *Proxies) candidates() []string {
+	seen := make(map[string]bool)
+	var addrs []string
+	for _, addr := range append(append([]string{}, p.configured...), p.discovered...) {
+		if !seen[addr] {
+			seen[addr] = true
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs
+}
+
+// Get returns the proxy to use. Endpoints preferred to the current one are
+// checked again once their retry delay expired, so that the plugin fails back
+// when they recover
+func (p *Proxies) Get() (string, error) {
+	p.Lock()
+	defer p.Unlock()
+	now := p.now()
+	candidates := p.candidates()
+	for _, addr := range candidates {
+		if addr == p.current && !p.failed {
+			return addr, nil
+		}
+		if now.Before(p.retry[addr]) {
+			continue
+		}
+		if err := p.check(addr, p.ns); err != nil {
+			logger.Debug("Proxy health check failed", "ns", p.ns, "proxy", addr, "err", err)
+			p.retry[addr] = now.Add(proxyRetryDelay)
+			continue
+		}
+		delete(p.retry, addr)
+		if p.current != "" && addr != p.current {
+			logger.Warn("Switching proxy", "ns", p.ns, "from", p.current, "to", addr)
+		}
+		p.current, p.failed = addr, false
+		return addr, nil
+	}
+	p.failed = true
+	return "", fmt.Errorf("no proxy answering for %s among %s", p.ns, strings.Join(candidates, ", "))
+}
+
+// Failed marks a proxy as unavailable, so that the next Get looks for another
+// and does not check it again before the retry delay
+func (p *Proxies) Failed(proxyURL string) {
+	p.Lock()
+	defer p.Unlock()
+	if proxyURL == p.current {
+		p.failed = true
+	}
+	p.retry[proxyURL] = p.now().Add(proxyRetryDelay)
+}
+
+// inUse returns the proxy in use, if it did not fail since it was selected
+func (p *Proxies) inUse() (string, bool) {
+	p.Lock()
+	defer p.Unlock()
+	return p.current, p.current != "" && !p.failed
+}
+
+// Discovered sets the proxies registered in the conscience, tried after the
+// configured ones
+func (p *Proxies) Discovered(addrs []string) {
+	p.Lock()
+	defer p.Unlock()
+	p.discovered = addrs
+}
+
+// report sends the position of the proxy in use among the candidates, and its
+// address as label
+func (p *Proxies) report(c chan netdata.Metric) {
+	p.Lock()
+	defer p.Unlock()
+	candidates := p.candidates()
+	for i, addr := range candidates {
+		if addr == p.current {
+			proxyChart(p.ns, addr, i, len(candidates), c)
+			return
+		}
+	}
+}
+
+func proxyChart(ns string, addr string, index int, count int, c chan netdata.Metric) {
+	chart := "proxy_" + ns
+	netdata.Update(chart, "index", fmt.Sprint(index), c)
+	netdata.Update(chart, "candidates", fmt.Sprint(count), c)
+	netdata.Label(chart, "addr", addr, c)
+}
+
+// proxy -- proxy a collection goes through. When proxies is set, calls follow
+// the proxy in use and fail over to the next one on a transport error
+type proxy struct {
+	url     string
+	proxies *Proxies
+}
+
+// get requests path through the proxy. On a transport error the proxy is
+// marked failed and the request is retried once through the next available
+// one
+func (p proxy) get(path string) (string, error) {
+	url := p.url
+	if p.proxies != nil {
+		if current, ok := p.proxies.inUse(); ok {
+			url = current
+		}
+	}
+	res, err := util.HTTPGet(util.URL(url, path))
+	if err == nil || p.proxies == nil {
+		return res, err
+	}
+	p.proxies.Failed(url)
+	next, nextErr := p.proxies.Get()
+	if nextErr != nil || next == url {
+		return res, err
+	}
+	logger.Warn("Proxy call failed, retrying through another proxy", "ns", p.proxies.ns, "proxy", url, "next", next, "err", err)
+	return util.HTTPGet(util.URL(next, path))
+}
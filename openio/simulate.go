@@ -0,0 +1,205 @@
+// OpenIO netdata collectors
+// Copyright (C) 2019 OpenIO SAS
+//
+// This library is free software; you can redistribute it and/or
+// modify it under the terms of the GNU Lesser General Public
+// License as published by the Free Software Foundation; either
+// version 3.0 of the License, or (at your option) any later version.
+//
+// This library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package openio
+
+import (
+	"fmt"
+	"oionetdata/netdata"
+	"oionetdata/util"
+	"time"
+)
+
+var simulations = make(map[string]*util.Simulation)
+
+// simulatedServices -- local services of a simulated namespace, per type
+var simulatedServices = map[string][]string{
+	"rawx":     {"127.0.0.1:6200", "127.0.0.1:6201", "127.0.0.1:6202"},
+	"meta0":    {"127.0.0.1:6001"},
+	"meta1":    {"127.0.0.1:6110"},
+	"meta2":    {"127.0.0.1:6120", "127.0.0.1:6121"},
+	"rdir":     {"127.0.0.1:6300"},
+	"account":  {"127.0.0.1:6009"},
+	"oioproxy": {"127.0.0.1:6006"},
+	"sqlx":     {"127.0.0.1:6130"},
+}
+
+var simulatedMethods = []string{"put", "copy", "get", "head", "del", "stat", "info", "raw", "other"}
+
+/*
+Simulate - send synthetic metrics with the charts and dimensions of Collect
+*/
+func Simulate(ns string, c chan netdata.Metric) {
+	sim, ok := simulations[ns]
+	if !ok {
+		sim = util.NewSimulation("openio/" + ns)
+		simulations[ns] = sim
+	}
+	now := time.Now()
+	proxyChart(ns, simulatedServices["oioproxy"][0], 0, 1, c)
+	for sType, services := range simulatedServices {
+		up, locked := 0, 0
+		for i, addr := range services {
+			sid := util.SID(sType+"_"+addr, ns)
+			netdata.Update("score", sid, sim.Gauge("score"+sType+addr, 60, 100), c)
+			tags := serviceTags{
+				Up:    sim.GaugeValue("up"+sType+addr, 0, 100) > 5,
+				Lock:  sim.GaugeValue("lock"+sType+addr, 0, 100) > 90,
+				Loc:   fmt.Sprintf("simulated.%d", i),
+				Slots: sType,
+				Vol:   fmt.Sprintf("/mnt/simulated/%s-%d", sType, i),
+			}
+			tags.Stats = map[string]float64{
+				"space": sim.GaugeValue("space"+sType+addr, 20, 100),
+				"cpu":   sim.GaugeValue("cpu"+sType+addr, 40, 100),
+				"io":    sim.GaugeValue("io"+sType+addr, 50, 100),
+			}
+			serviceStats(sid, tags, c)
+			if tags.Up {
+				up++
+			}
+			if tags.Lock {
+				locked++
+			}
+			serviceStatus(sid, sType, addr, tags, c)
+			switch sType {
+			case "rawx":
+				simulateRequests(sim, ns, sType, addr, now, c)
+				simulateVolume(sim, ns, addr, c)
+			case "oioproxy", "sqlx":
+				simulateRequests(sim, ns, sType, addr, now, c)
+			case "rdir":
+				// Forward stats, completed by the /status gauges
+				simulateRequests(sim, ns, sType, addr, now, c)
+				netdata.Update("rdir_opened_db_count", util.SID(addr, ns), sim.Gauge(addr+"opened_db_count", 1, 50), c)
+			case "account":
+				simulateRequests(sim, ns, sType, addr, now, c)
+				netdata.Update("account_account_count", util.SID(addr, ns), sim.Gauge(addr+"account_count", 100, 5000), c)
+			case "meta0", "meta1", "meta2":
+				simulateMetax(sim, ns, sType, addr, now, c)
+				simulateMetaInfo(sim, ns, sType, addr, c)
+			}
+		}
+		serviceCounts(ns, sType, len(services), up, locked, c)
+	}
+}
+
+func simulateRequests(sim *util.Simulation, ns string, sType string, service string, now time.Time, c chan netdata.Metric) {
+	counters := map[string]string{
+		"req.hits":       sim.Counter(service+"req.hits", 200),
+		"req.time":       sim.Counter(service+"req.time", 5e5),
+		"rep.hits.2xx":   sim.Counter(service+"rep.hits.2xx", 190),
+		"rep.hits.4xx":   sim.Counter(service+"rep.hits.4xx", 8),
+		"rep.hits.5xx":   sim.Counter(service+"rep.hits.5xx", 1),
+		"rep.hits.other": sim.Counter(service+"rep.hits.other", 0.1),
+		"rep.hits.403":   sim.Counter(service+"rep.hits.403", 1),
+		"rep.hits.404":   sim.Counter(service+"rep.hits.404", 6),
+		"rep.bread":      sim.Counter(service+"rep.bread", 5e7),
+		"rep.bwritten":   sim.Counter(service+"rep.bwritten", 2e7),
+	}
+	for _, method := range simulatedMethods {
+		counters["req.hits."+method] = sim.Counter(service+"req.hits."+method, 20)
+		counters["req.time."+method] = sim.Counter(service+"req.time."+method, 5e4)
+	}
+	requestCharts(ns, sType, service, counters, now, c)
+	statCharts(ns, sType, service, stat{config: map[string]string{"service_id": service}}, true, c)
+}
+
+func simulateVolume(sim *util.Simulation, ns string, service string, c chan netdata.Metric) {
+	volume := util.SID(service, ns, "simulated")
+	total := 4e12
+	used := sim.GaugeValue(service+"byte_used", 1e12, 3e12)
+	netdata.Update("byte_used", volume, fmt.Sprint(uint64(used)), c)
+	netdata.Update("byte_free", volume, fmt.Sprint(uint64(total-used)), c)
+	netdata.Update("byte_avail", volume, fmt.Sprint(uint64(0.95*(total-used))), c)
+	netdata.Update("inodes_used", volume, sim.Gauge(service+"inodes_used", 1e6, 5e6), c)
+	netdata.Update("inodes_free", volume, sim.Gauge(service+"inodes_free", 2e8, 2.5e8), c)
+
+	sid := util.SID(service, ns)
+	for _, chart := range []string{"disk_ops_", "disk_bytes_", "disk_latency_", "disk_queue_", "disk_util_"} {
+		netdata.Label(chart+sid, "device", fmt.Sprintf("sd%c", 'b'+len(service)%8), c)
+	}
+	netdata.Update("disk_ops_"+sid, "read", sim.Gauge(service+"disk_reads", 50, 400), c)
+	netdata.Update("disk_ops_"+sid, "write", sim.Gauge(service+"disk_writes", 20, 200), c)
+	netdata.Update("disk_bytes_"+sid, "read", sim.Gauge(service+"disk_bread", 5e6, 1e8), c)
+	netdata.Update("disk_bytes_"+sid, "written", sim.Gauge(service+"disk_bwritten", 2e6, 5e7), c)
+	netdata.Update("disk_latency_"+sid, "read", sim.Gauge(service+"disk_rlatency", 500, 20000), c)
+	netdata.Update("disk_latency_"+sid, "write", sim.Gauge(service+"disk_wlatency", 500, 30000), c)
+	netdata.Update("disk_queue_"+sid, "in_flight", sim.Gauge(service+"disk_inflight", 0, 16), c)
+	netdata.Update("disk_queue_"+sid, "avg", sim.Gauge(service+"disk_queue", 0, 8), c)
+	netdata.Update("disk_util_"+sid, "busy", sim.Gauge(service+"disk_busy", 5, 100), c)
+}
+
+func simulateMetax(sim *util.Simulation, ns string, sType string, service string, now time.Time, c chan netdata.Metric) {
+	sid := util.SID(service, ns)
+	for metric, rate := range map[string]float64{"req.hits": 100, "req.time": 2e5} {
+		if diff := diffCounter(metric, sid, sim.Counter(service+metric, rate), now); diff != "" {
+			netdata.Update(metric, sid, diff, c)
+		}
+	}
+	statCharts(ns, sType, service, stat{
+		gauges: map[string]string{"cnx.client": sim.Gauge(service+"cnx.client", 5, 50)},
+		config: map[string]string{"service_id": service},
+	}, true, c)
+}
+
+func simulateMetaInfo(sim *util.Simulation, ns string, sType string, service string, c chan netdata.Metric) {
+	info := make(map[string]interface{})
+	sections := map[string][]string{
+		"cache":     {"cold", "hot", "max", "used"},
+		"elections": {"none", "pending", "master", "slave", "failed"},
+		"threads":   {"active", "idle", "max"},
+		"sqlite":    {"open", "max"},
+	}
+	for section, fields := range sections {
+		values := make(map[string]interface{})
+		for _, field := range fields {
+			values[field] = sim.GaugeValue(service+section+field, 0, 1000)
+		}
+		info[section] = values
+	}
+	metaInfoCharts(ns, sType, service, info, c)
+}
+
+// simulatedClusterSize -- number of services per type in a simulated namespace
+const simulatedClusterSize = 12
+
+/*
+SimulateCluster - send synthetic metrics with the charts and dimensions of
+CollectCluster
+*/
+func SimulateCluster(ns string, c chan netdata.Metric) {
+	sim, ok := simulations[ns]
+	if !ok {
+		sim = util.NewSimulation("openio/" + ns)
+		simulations[ns] = sim
+	}
+	proxyChart(ns, simulatedServices["oioproxy"][0], 0, 1, c)
+	for sType := range simulatedServices {
+		sInfo := make(serviceInfo, simulatedClusterSize)
+		for i := range sInfo {
+			key := fmt.Sprintf("cluster%s%d", sType, i)
+			sInfo[i].Addr = fmt.Sprintf("10.0.0.%d:6000", i+1)
+			sInfo[i].Score = int(sim.GaugeValue(key+"score", -20, 100))
+			if sInfo[i].Score < 0 {
+				sInfo[i].Score = 0
+			}
+			sInfo[i].Tags.Up = sInfo[i].Score > 0
+			sInfo[i].Tags.Lock = sim.GaugeValue(key+"lock", 0, 100) > 90
+		}
+		clusterAggregates(ns, sType, sInfo, c)
+	}
+}
@@ -0,0 +1,107 @@
+// OpenIO netdata collectors
+// Copyright (C) 2019 OpenIO SAS
+//
+// This library is free software; you can redistribute it and/or
+// modify it under the terms of the GNU Lesser General Public
+// License as published by the Free Software Foundation; either
+// version 3.0 of the License, or (at your option) any later version.
+//
+// This library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package redis
+
+import (
+	"oionetdata/netdata"
+	"oionetdata/util"
+)
+
+func init() {
+	netdata.Register("redis", []netdata.Param{
+		{Name: "target", Description: util.TargetUsage, Required: true},
+	}, func(w netdata.Worker, params netdata.Params) error {
+		target, err := util.ParseTarget(params["target"])
+		if err != nil {
+			return err
+		}
+		return AddCharts(w, target)
+	})
+}
+
+// AddCharts adds a collector for target and its charts to a worker
+func AddCharts(w netdata.Worker, target util.Target) error {
+	// CLUSTER_ID is used exclusively as a label here; it allows to group metrics by cluster to provide
+	// alerts when the cluster size/state is incorrect
+	cluster := target.Params.Get("cluster")
+	if len(target.Extra) > 0 {
+		cluster = target.Extra[0]
+	}
+	var collector netdata.Collector = NewTargetCollector(target)
+	if util.Simulate {
+		collector = NewSimulatedCollector(target)
+	}
+	w.AddCollector(collector)
+	instance := "redis." + target.Name
+	if cluster != "" {
+		instance += ":" + cluster
+	}
+	w.AddTarget(collector, instance, instance, "redis")
+
+	keysChart := netdata.NewChart(instance, "keys", "", "Keys", "count", instance, "redis.keys.")
+	keysChart.AddDimension("keys", "keys", netdata.AbsoluteAlgorithm)
+	w.AddChart(keysChart, collector)
+
+	memChart := netdata.NewChart(instance, "memory", "", "Memory", "bytes", instance, "redis.memory")
+	memChart.AddDimension("used_memory", "total", netdata.AbsoluteAlgorithm)
+	memChart.AddDimension("used_memory_rss", "rss", netdata.AbsoluteAlgorithm)
+	memChart.AddDimension("used_memory_lua", "lua", netdata.AbsoluteAlgorithm)
+	w.AddChart(memChart, collector)
+
+	bandwidthChart := netdata.NewChart(instance, "net", "", "Network traffic", "bytes", instance, "redis.net")
+	bandwidthChart.AddDimension("total_net_input_bytes", "received", netdata.IncrementalAlgorithm)
+	bandwidthChart.AddDimension("total_net_output_bytes", "sent", netdata.IncrementalAlgorithm)
+	w.AddChart(bandwidthChart, collector)
+
+	opsChart := netdata.NewChart(instance, "instant", "", "Instantaneous operations", "ops", instance, "redis.ops")
+	opsChart.AddDimension("instantaneous_ops_per_sec", "ops", netdata.AbsoluteAlgorithm)
+	w.AddChart(opsChart, collector)
+
+	masterChart := netdata.NewChart(instance, "state", "", "Instance is master", "master", instance, "redis.master")
+	masterChart.AddDimension("is_master", "state", netdata.AbsoluteAlgorithm)
+	w.AddChart(masterChart, collector)
+
+	replicaCharts := netdata.NewChart(instance, "replicas", "", "Replicas", "count", instance, "redis.replicas")
+	replicaCharts.AddDimension("connected_slaves", "replicas", netdata.AbsoluteAlgorithm)
+	w.AddChart(replicaCharts, collector)
+
+	cacheCharts := netdata.NewChart(instance, "cache", "", "Cache", "ops", instance, "redis.cache")
+	cacheCharts.AddDimension("keyspace_hits", "hits", netdata.AbsoluteAlgorithm)
+	cacheCharts.AddDimension("keyspace_misses", "misses", netdata.AbsoluteAlgorithm)
+	w.AddChart(cacheCharts, collector)
+
+	backlogCharts := netdata.NewChart(instance, "backlog", "", "Backlog", "bytes", instance, "redis.backlog")
+	backlogCharts.AddDimension("repl_backlog_size", "backlog", netdata.AbsoluteAlgorithm)
+	w.AddChart(backlogCharts, collector)
+
+	changesSinceSave := netdata.NewChart(instance, "changes", "", "Changes since last save", "ops", instance, "redis.changes")
+	changesSinceSave.AddDimension("rdb_changes_since_last_save", "changes", netdata.AbsoluteAlgorithm)
+	w.AddChart(changesSinceSave, collector)
+
+	connCharts := netdata.NewChart(instance, "connections", "", "Connections", "count", instance, "redis.connections")
+	connCharts.AddDimension("total_connections_received", "connections", netdata.AbsoluteAlgorithm)
+	w.AddChart(connCharts, collector)
+
+	commandCharts := netdata.NewChart(instance, "commands", "", "Commands", "count", instance, "redis.commands")
+	commandCharts.AddDimension("total_commands_processed", "commands", netdata.AbsoluteAlgorithm)
+	w.AddChart(commandCharts, collector)
+
+	memFragmentCharts := netdata.NewChart(instance, "fragmentation", "", "Memory fragmentation", "ratio", instance, "redis.fragmentation")
+	memFragmentCharts.AddDimension("mem_fragmentation_ratio", "fragmentation", netdata.AbsoluteAlgorithm)
+	w.AddChart(memFragmentCharts, collector)
+	return nil
+}
@@ -18,23 +18,33 @@ package redis
 
 import (
 	"bufio"
-	"fmt"
-	"net"
-	"os"
+	"oionetdata/logger"
+	"oionetdata/netdata"
+	"oionetdata/util"
 	"regexp"
 	"strings"
+	"time"
 )
 
 type collector struct {
-	addr    string
+	target      util.Target
+	connectTime time.Duration
 }
 
 func NewCollector(addr string) *collector {
+	return NewTargetCollector(util.Target{Network: "tcp", Addr: addr, Name: addr})
+}
+
+func NewTargetCollector(target util.Target) *collector {
 	return &collector{
-		addr:    addr,
+		target: target,
 	}
 }
 
+func (c *collector) ConnectTime() time.Duration {
+	return c.connectTime
+}
+
 var whitelist = map[string]bool{
 	"used_memory":                 true,
 	"used_memory_rss":             true,
@@ -57,11 +67,12 @@ var whitelist = map[string]bool{
 var keysRegexp = regexp.MustCompile(`keys=(\d+)`)
 
 func (c *collector) Collect() (map[string]string, error) {
-	conn, err := net.Dial("tcp", c.addr)
+	conn, connectTime, err := c.target.Dial(util.DefaultDialTimeout)
 	if err != nil {
 		return nil, err
 	}
 	defer conn.Close()
+	c.connectTime = connectTime
 
 	_, err = conn.Write([]byte("INFO\r\nQUIT\r\n"))
 	if err != nil {
@@ -70,11 +81,20 @@ func (c *collector) Collect() (map[string]string, error) {
 
 	data := map[string]string{}
 
+	// Lines that are neither fields, section headers nor protocol replies,
+	// reported if no field could be read
+	var malformed []string
 	scanner := bufio.NewScanner(conn)
 	for scanner.Scan() {
 		line := scanner.Text()
+		if strings.HasPrefix(line, "-") {
+			return nil, netdata.ProtocolErrorf("error from %s: %s", c.target, strings.TrimPrefix(line, "-"))
+		}
 		kv := strings.Split(line, ":")
 		if len(kv) != 2 {
+			if line != "" && !strings.ContainsAny(line[:1], "#$+") {
+				malformed = append(malformed, line)
+			}
 			continue
 		}
 		if _, ok := whitelist[kv[0]]; ok {
@@ -84,7 +104,7 @@ func (c *collector) Collect() (map[string]string, error) {
 				if len(keys) > 1 {
 					data["keys"] = keys[1]
 				} else {
-					fmt.Fprintln(os.Stderr, "WARN: received unparseable db notation", kv[1])
+					logger.Warn("Received unparseable db notation", "target", c.target.String(), "value", kv[1])
 				}
 			// Format role:master or role:slave
 			} else if kv[0] == "role" {
@@ -103,5 +123,11 @@ func (c *collector) Collect() (map[string]string, error) {
 	if err := scanner.Err(); err != nil {
 		return nil, err
 	}
+	if len(data) == 0 && len(malformed) > 0 {
+		return nil, netdata.ParseErrorf("could not parse response from %s: %q", c.target, malformed[0])
+	}
+	if len(data) == 0 {
+		return nil, netdata.ProtocolErrorf("no INFO fields in response from %s", c.target)
+	}
 	return data, nil
 }
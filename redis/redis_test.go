@@ -24,6 +24,8 @@ import (
 	"net"
 	"reflect"
 	"testing"
+
+	"oionetdata/util"
 )
 
 type testServer struct {
@@ -113,3 +115,16 @@ func TestRedisCollector(t *testing.T) {
 		t.Fatalf("expected error")
 	}
 }
+
+func TestSimulatedCollector(t *testing.T) {
+	collector := NewSimulatedCollector(util.Target{Network: "tcp", Addr: "127.0.0.1:6379", Name: "simulated"})
+	data, err := collector.Collect()
+	if err != nil {
+		t.Fatalf("unexpected Collect error: %v", err)
+	}
+	for key := range expected {
+		if _, ok := data[key]; !ok {
+			t.Fatalf("missing simulated key %s", key)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+// OpenIO netdata collectors
+// Copyright (C) 2019 OpenIO SAS
+//
+// This library is free software; you can redistribute it and/or
+// modify it under the terms of the GNU Lesser General Public
+// License as published by the Free Software Foundation; either
+// version 3.0 of the License, or (at your option) any later version.
+//
+// This library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package redis
+
+import (
+	"oionetdata/util"
+	"time"
+)
+
+type simulator struct {
+	sim *util.Simulation
+}
+
+// NewSimulatedCollector returns a collector producing synthetic INFO fields
+// for target, without connecting to it
+func NewSimulatedCollector(target util.Target) *simulator {
+	return &simulator{
+		sim: util.NewSimulation("redis/" + target.String()),
+	}
+}
+
+func (s *simulator) ConnectTime() time.Duration {
+	return time.Duration(s.sim.GaugeValue("connect_time", 100, 2000)) * time.Microsecond
+}
+
+func (s *simulator) Collect() (map[string]string, error) {
+	return map[string]string{
+		"used_memory":                 s.sim.Gauge("used_memory", 5e7, 6e7),
+		"used_memory_rss":             s.sim.Gauge("used_memory_rss", 6e7, 7e7),
+		"used_memory_lua":             s.sim.Gauge("used_memory_lua", 3e4, 4e4),
+		"mem_fragmentation_ratio":     s.sim.Ratio("mem_fragmentation_ratio", 1, 1.5),
+		"rdb_changes_since_last_save": s.sim.Gauge("rdb_changes_since_last_save", 0, 1000),
+		"total_connections_received":  s.sim.Counter("total_connections_received", 5),
+		"total_commands_processed":    s.sim.Counter("total_commands_processed", 5000),
+		"instantaneous_ops_per_sec":   s.sim.Gauge("instantaneous_ops_per_sec", 100, 1000),
+		"total_net_input_bytes":       s.sim.Counter("total_net_input_bytes", 2e5),
+		"total_net_output_bytes":      s.sim.Counter("total_net_output_bytes", 5e5),
+		"keyspace_hits":               s.sim.Counter("keyspace_hits", 800),
+		"keyspace_misses":             s.sim.Counter("keyspace_misses", 50),
+		"is_master":                   "1",
+		"connected_slaves":            "1",
+		"repl_backlog_size":           "1048576",
+		"keys":                        s.sim.Gauge("keys", 1e4, 2e4),
+	}, nil
+}
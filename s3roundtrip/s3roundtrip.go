@@ -83,29 +83,43 @@ type collector struct {
 	s3c          *s3c
 }
 
-func NewCollector(conf map[string]string, requests []string) *collector {
+// CheckConfig reports the first invalid or missing setting of a roundtrip
+// configuration
+func CheckConfig(conf map[string]string) error {
 	for _, key := range []string{"endpoint", "access", "secret", "region", "bucket", "object"} {
 		if _, ok := conf[key]; !ok {
-			log.Fatalf("ERROR: cannot load S3 roundtrip collector: missing '%s' key from config", key)
+			return fmt.Errorf("missing '%s' key from config", key)
+		}
+	}
+	for _, key := range []string{"timeout", "mpu_size", "size"} {
+		if v, ok := conf[key]; ok {
+			if _, err := strconv.Atoi(v); err != nil {
+				return fmt.Errorf("invalid value for %s, need integer: %s", key, v)
+			}
 		}
 	}
+	if v, ok := conf["mpu_size"]; ok {
+		if value, _ := strconv.Atoi(v); value < 5*1024*1024 {
+			return fmt.Errorf("configured MPU size is lower than minimum MPU size")
+		}
+	}
+	return nil
+}
+
+func NewCollector(conf map[string]string, requests []string) *collector {
+	if err := CheckConfig(conf); err != nil {
+		log.Fatalln("ERROR: cannot load S3 roundtrip collector:", err)
+	}
 
 	var timeout = 15 * time.Second
 	if t, ok := conf["timeout"]; ok {
-		value, err := strconv.Atoi(t)
-		if err != nil {
-			log.Fatalln("Invalid value for timeout, need integer", value)
-		}
+		value, _ := strconv.Atoi(t)
 		timeout = time.Duration(value) * time.Second
 	}
 
 	var mpuSize = 5 * 1024 * 1024
 	if t, ok := conf["mpu_size"]; ok {
-		value, _ := strconv.Atoi(t)
-		if value < mpuSize {
-			log.Fatalln("Won't proceed; configured MPU size is lower that minimum MPU Size")
-		}
-		mpuSize = value
+		mpuSize, _ = strconv.Atoi(t)
 	}
 
 	fileSize := mpuSize - 1
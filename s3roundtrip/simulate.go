@@ -0,0 +1,55 @@
+// OpenIO netdata collectors
+// Copyright (C) 2019 OpenIO SAS
+//
+// This library is free software; you can redistribute it and/or
+// modify it under the terms of the GNU Lesser General Public
+// License as published by the Free Software Foundation; either
+// version 3.0 of the License, or (at your option) any later version.
+//
+// This library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package s3roundtrip
+
+import (
+	"fmt"
+	"oionetdata/util"
+)
+
+type simulator struct {
+	requests []string
+	sim      *util.Simulation
+}
+
+// NewSimulatedCollector returns a collector producing synthetic roundtrip
+// results for requests, without contacting the endpoint
+func NewSimulatedCollector(endpoint string, requests []string) *simulator {
+	return &simulator{
+		requests: requests,
+		sim:      util.NewSimulation("s3roundtrip/" + endpoint),
+	}
+}
+
+func (s *simulator) Collect() (map[string]string, error) {
+	data := map[string]string{}
+	for _, req := range s.requests {
+		for _, dim := range []string{"2xx", "4xx", "5xx", "other"} {
+			data[fmt.Sprintf("response_code_%s_%s", req, dim)] = "0"
+		}
+		// Mostly successful requests, with an occasional server error
+		code := "2xx"
+		if s.sim.GaugeValue("error_"+req, 0, 100) > 97 {
+			code = "5xx"
+		}
+		data[fmt.Sprintf("response_code_%s_%s", req, code)] = "1"
+		data[fmt.Sprintf("response_time_%s", req)] = s.sim.Gauge("response_time_"+req, 5, 200)
+	}
+	data["ttfb_put"] = s.sim.Gauge("ttfb_put", 5, 50)
+	data["ttfb_get"] = s.sim.Gauge("ttfb_get", 5, 50)
+	return data, nil
+}
@@ -0,0 +1,85 @@
+// OpenIO netdata collectors
+// Copyright (C) 2019 OpenIO SAS
+//
+// This library is free software; you can redistribute it and/or
+// modify it under the terms of the GNU Lesser General Public
+// License as published by the Free Software Foundation; either
+// version 3.0 of the License, or (at your option) any later version.
+//
+// This library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package util
+
+import (
+	"flag"
+	"fmt"
+	"io"
+)
+
+// Check -- pass/fail report of a plugin configuration check
+type Check struct {
+	out    io.Writer
+	passed int
+	failed int
+}
+
+// CheckFlag registers the --check flag on a plugin flag set
+func CheckFlag(fs *flag.FlagSet) *bool {
+	return fs.Bool("check", false, "Validate configuration, try to reach each target, print a report and exit")
+}
+
+// NewCheck returns a report printed to out
+func NewCheck(out io.Writer) *Check {
+	return &Check{out: out}
+}
+
+// Run records the outcome of one verification and returns whether it passed
+func (c *Check) Run(item string, err error) bool {
+	if err != nil {
+		c.failed++
+		fmt.Fprintf(c.out, "FAIL  %s: %v\n", item, err)
+		return false
+	}
+	c.passed++
+	fmt.Fprintf(c.out, "PASS  %s\n", item)
+	return true
+}
+
+// Target checks that a connection to target can be established
+func (c *Check) Target(target Target) bool {
+	conn, elapsed, err := target.Dial(DefaultDialTimeout)
+	if err != nil {
+		return c.Run("connect to "+target.String(), err)
+	}
+	conn.Close()
+	return c.Run(fmt.Sprintf("connect to %s (%v)", target.String(), elapsed), nil)
+}
+
+// Addr checks that a TCP connection to addr can be established
+func (c *Check) Addr(addr string) bool {
+	return c.Target(Target{Network: "tcp", Addr: addr, Name: addr})
+}
+
+// HTTP checks that url answers a GET request successfully
+func (c *Check) HTTP(url string) bool {
+	_, status, err := httpClient.get(url)
+	if err == nil && status >= 400 {
+		err = fmt.Errorf("HTTP status %d", status)
+	}
+	return c.Run("GET "+url, err)
+}
+
+// Done prints the summary and returns the exit code of the check
+func (c *Check) Done() int {
+	fmt.Fprintf(c.out, "%d passed, %d failed\n", c.passed, c.failed)
+	if c.failed > 0 {
+		return 1
+	}
+	return 0
+}
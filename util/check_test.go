@@ -0,0 +1,63 @@
+// OpenIO netdata collectors
+// Copyright (C) 2019 OpenIO SAS
+//
+// This library is free software; you can redistribute it and/or
+// modify it under the terms of the GNU Lesser General Public
+// License as published by the Free Software Foundation; either
+// version 3.0 of the License, or (at your option) any later version.
+//
+// This library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package util
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	addr := strings.TrimPrefix(srv.URL, "http://")
+
+	var out bytes.Buffer
+	check := NewCheck(&out)
+	if !check.Run("config", nil) || !check.Addr(addr) || !check.HTTP(srv.URL+"/stats") {
+		t.Fatalf("unexpected failure\n%s", out.String())
+	}
+	if code := check.Done(); code != 0 {
+		t.Fatalf("expected exit code 0, got %d\n%s", code, out.String())
+	}
+
+	out.Reset()
+	check = NewCheck(&out)
+	if check.Run("config", errors.New("bad key")) || check.HTTP(srv.URL+"/missing") {
+		t.Fatalf("unexpected success\n%s", out.String())
+	}
+	srv.Close()
+	if check.Addr(addr) {
+		t.Fatalf("unexpected success\n%s", out.String())
+	}
+	if code := check.Done(); code != 1 {
+		t.Fatalf("expected exit code 1, got %d\n%s", code, out.String())
+	}
+	for _, expected := range []string{"FAIL  config: bad key\n", "HTTP status 404", "0 passed, 3 failed\n"} {
+		if !strings.Contains(out.String(), expected) {
+			t.Fatalf("expected %q in report\n%s", expected, out.String())
+		}
+	}
+}
@@ -0,0 +1,41 @@
+// OpenIO netdata collectors
+// Copyright (C) 2019 OpenIO SAS
+//
+// This library is free software; you can redistribute it and/or
+// modify it under the terms of the GNU Lesser General Public
+// License as published by the Free Software Foundation; either
+// version 3.0 of the License, or (at your option) any later version.
+//
+// This library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package util
+
+import (
+	"net"
+	"time"
+)
+
+// DefaultDialTimeout -- timeout to connect to a target and exchange with it
+const DefaultDialTimeout = 5 * time.Second
+
+// Dial connects to a target and sets a deadline for the whole exchange. It
+// also returns the time it took to establish the connection
+func Dial(network string, addr string, timeout time.Duration) (net.Conn, time.Duration, error) {
+	start := time.Now()
+	conn, err := net.DialTimeout(network, addr, timeout)
+	if err != nil {
+		return nil, 0, err
+	}
+	connectTime := time.Since(start)
+	if err := conn.SetDeadline(start.Add(timeout)); err != nil {
+		conn.Close()
+		return nil, 0, err
+	}
+	return conn, connectTime, nil
+}
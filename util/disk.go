@@ -0,0 +1,175 @@
+// OpenIO netdata collectors
+// Copyright (C) 2019 OpenIO SAS
+//
+// This library is free software; you can redistribute it and/or
+// modify it under the terms of the GNU Lesser General Public
+// License as published by the Free Software Foundation; either
+// version 3.0 of the License, or (at your option) any later version.
+//
+// This library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package util
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+var mountInfoPath = "/proc/self/mountinfo"
+var diskStatsPath = "/proc/diskstats"
+
+// DiskStats -- counters of a block device from /proc/diskstats, times are in
+// milliseconds
+type DiskStats struct {
+	Device       string
+	Reads        uint64
+	ReadSectors  uint64
+	ReadTime     uint64
+	Writes       uint64
+	WriteSectors uint64
+	WriteTime    uint64
+	InFlight     uint64
+	IOTime       uint64
+	WeightedTime uint64
+}
+
+// SectorSize -- unit of the sector counters of /proc/diskstats, whatever the
+// actual sector size of the device
+const SectorSize = 512
+
+// VolumeDisk returns the counters of the block device backing volume
+func VolumeDisk(volume string) (DiskStats, error) {
+	major, minor, err := blockDevice(volume)
+	if err != nil {
+		return DiskStats{}, err
+	}
+	return diskStats(major, minor)
+}
+
+// devNumbers splits a Linux device number into major and minor
+func devNumbers(dev uint64) (uint64, uint64) {
+	major := (dev>>8)&0xfff | (dev>>32)&^0xfff
+	minor := dev&0xff | (dev>>12)&^0xff
+	return major, minor
+}
+
+// blockDevice returns the device numbers of the filesystem holding path. For
+// filesystems without a block device number of their own (btrfs, ZFS), the
+// source of the mount table entry is used instead
+func blockDevice(path string) (uint64, uint64, error) {
+	var st syscall.Stat_t
+	if err := syscall.Stat(path, &st); err != nil {
+		return 0, 0, err
+	}
+	major, minor := devNumbers(uint64(st.Dev))
+	if major != 0 {
+		return major, minor, nil
+	}
+	source, err := mountSource(path, fmt.Sprintf("%d:%d", major, minor))
+	if err != nil {
+		return 0, 0, err
+	}
+	if !strings.HasPrefix(source, "/dev/") {
+		return 0, 0, fmt.Errorf("%s is not backed by a block device (%s)", path, source)
+	}
+	if err := syscall.Stat(source, &st); err != nil {
+		return 0, 0, err
+	}
+	major, minor = devNumbers(uint64(st.Rdev))
+	return major, minor, nil
+}
+
+// mountSource returns the source of the deepest mount of device dev
+// (major:minor) containing path
+func mountSource(path string, dev string) (string, error) {
+	f, err := os.Open(mountInfoPath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	path, err = filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	source, mountPoint := "", ""
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// 36 35 0:42 / /mnt/data rw,noatime shared:1 - btrfs /dev/sdb1 rw
+		fields := strings.Fields(scanner.Text())
+		sep := -1
+		for i, field := range fields {
+			if field == "-" {
+				sep = i
+				break
+			}
+		}
+		if sep < 5 || len(fields) < sep+3 || fields[2] != dev {
+			continue
+		}
+		mp := fields[4]
+		if mp != "/" && path != mp && !strings.HasPrefix(path, mp+"/") {
+			continue
+		}
+		if len(mp) > len(mountPoint) || source == "" {
+			source, mountPoint = fields[sep+2], mp
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	if source == "" {
+		return "", fmt.Errorf("no mount of device %s found for %s", dev, path)
+	}
+	return source, nil
+}
+
+// diskStats reads the counters of device major:minor
+func diskStats(major uint64, minor uint64) (DiskStats, error) {
+	f, err := os.Open(diskStatsPath)
+	if err != nil {
+		return DiskStats{}, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 14 || fields[0] != strconv.FormatUint(major, 10) || fields[1] != strconv.FormatUint(minor, 10) {
+			continue
+		}
+		var values [11]uint64
+		for i := range values {
+			if values[i], err = strconv.ParseUint(fields[i+3], 10, 64); err != nil {
+				return DiskStats{}, fmt.Errorf("%s: %v", diskStatsPath, err)
+			}
+		}
+		return DiskStats{
+			Device:       fields[2],
+			Reads:        values[0],
+			ReadSectors:  values[2],
+			ReadTime:     values[3],
+			Writes:       values[4],
+			WriteSectors: values[6],
+			WriteTime:    values[7],
+			InFlight:     values[8],
+			IOTime:       values[9],
+			WeightedTime: values[10],
+		}, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return DiskStats{}, err
+	}
+	return DiskStats{}, fmt.Errorf("device %d:%d not found in %s", major, minor, diskStatsPath)
+}
@@ -0,0 +1,117 @@
+// OpenIO netdata collectors
+// Copyright (C) 2019 OpenIO SAS
+//
+// This library is free software; you can redistribute it and/or
+// modify it under the terms of the GNU Lesser General Public
+// License as published by the Free Software Foundation; either
+// version 3.0 of the License, or (at your option) any later version.
+//
+// This library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package util
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeProcFile(t *testing.T, dir string, name string, content string) string {
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestDevNumbers(t *testing.T) {
+	tests := []struct {
+		dev          uint64
+		major, minor uint64
+	}{
+		{0x0811, 8, 17},
+		{0xfe00, 254, 0},
+		{0x1000fd01, 253, 65537},
+	}
+	for _, test := range tests {
+		if major, minor := devNumbers(test.dev); major != test.major || minor != test.minor {
+			t.Errorf("%#x: expected %d:%d, got %d:%d", test.dev, test.major, test.minor, major, minor)
+		}
+	}
+}
+
+func TestMountSource(t *testing.T) {
+	dir, err := ioutil.TempDir("", "disk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(path string) { mountInfoPath = path }(mountInfoPath)
+	mountInfoPath = writeProcFile(t, dir, "mountinfo", `22 1 8:1 / / rw,relatime shared:1 - ext4 /dev/sda1 rw
+36 22 0:42 / /mnt/data rw,noatime shared:2 - btrfs /dev/sdb rw,space_cache
+37 36 0:42 /rawx /mnt/data/OPENIO/rawx-1 rw,noatime shared:2 - btrfs /dev/sdc rw
+38 22 0:43 / /mnt/data-old rw - tmpfs tmpfs rw
+`)
+
+	tests := []struct {
+		path, dev, source string
+	}{
+		{"/mnt/data/OPENIO/meta2-1", "0:42", "/dev/sdb"},
+		{"/mnt/data/OPENIO/rawx-1/0A1", "0:42", "/dev/sdc"},
+		{"/mnt/data-old/rawx", "0:43", "tmpfs"},
+	}
+	for _, test := range tests {
+		source, err := mountSource(test.path, test.dev)
+		if err != nil || source != test.source {
+			t.Errorf("%s: expected %s, got %q %v", test.path, test.source, source, err)
+		}
+	}
+	if source, err := mountSource("/srv", "0:44"); err == nil {
+		t.Errorf("expected an error, got %q", source)
+	}
+}
+
+func TestDiskStats(t *testing.T) {
+	dir, err := ioutil.TempDir("", "disk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(path string) { diskStatsPath = path }(diskStatsPath)
+	diskStatsPath = writeProcFile(t, dir, "diskstats", `   8       0 sda 120 3 2400 80 60 5 960 300 0 200 380 0 0 0 0
+   8      16 sdb 1000 10 64000 4000 500 20 32000 6000 2 3000 10000 0 0 0 0 10 20
+`)
+
+	stats, err := diskStats(8, 16)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := DiskStats{
+		Device: "sdb", Reads: 1000, ReadSectors: 64000, ReadTime: 4000,
+		Writes: 500, WriteSectors: 32000, WriteTime: 6000,
+		InFlight: 2, IOTime: 3000, WeightedTime: 10000,
+	}
+	if stats != expected {
+		t.Fatalf("expected %+v, got %+v", expected, stats)
+	}
+	if _, err := diskStats(8, 32); err == nil {
+		t.Fatalf("expected an error for a missing device")
+	}
+}
+
+func TestVolumeDisk(t *testing.T) {
+	stats, err := VolumeDisk("/")
+	if err != nil {
+		t.Skipf("no block device behind /: %v", err)
+	}
+	if stats.Device == "" {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+}
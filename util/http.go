@@ -0,0 +1,185 @@
+// OpenIO netdata collectors
+// Copyright (C) 2019 OpenIO SAS
+//
+// This library is free software; you can redistribute it and/or
+// modify it under the terms of the GNU Lesser General Public
+// License as published by the Free Software Foundation; either
+// version 3.0 of the License, or (at your option) any later version.
+//
+// This library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package util
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"time"
+)
+
+// DefaultHTTPTimeout -- timeout of a single HTTP request
+const DefaultHTTPTimeout = 5 * time.Second
+
+// HTTPConfig -- settings of the HTTP client shared by collectors
+type HTTPConfig struct {
+	Timeout time.Duration
+
+	// TLS; CAFile replaces the system pool, CertFile/KeyFile enable client auth
+	TLS      bool
+	CAFile   string
+	CertFile string
+	KeyFile  string
+	Insecure bool
+
+	// Authentication; Token takes precedence over Username/Password
+	Username string
+	Password string
+	Token    string
+}
+
+// HTTPClient -- HTTP client with connection pooling and authentication
+type HTTPClient struct {
+	client *http.Client
+	conf   HTTPConfig
+}
+
+var httpClient, _ = NewHTTPClient(HTTPConfig{Timeout: DefaultHTTPTimeout})
+
+// HTTPFlags registers the shared HTTP client flags on a plugin flag set
+func HTTPFlags(fs *flag.FlagSet) *HTTPConfig {
+	conf := &HTTPConfig{}
+	fs.DurationVar(&conf.Timeout, "http-timeout", DefaultHTTPTimeout, "Timeout of HTTP requests")
+	fs.BoolVar(&conf.TLS, "https", false, "Use https to reach services")
+	fs.StringVar(&conf.CAFile, "http-ca", "", "Path to CA certificate used to verify services")
+	fs.StringVar(&conf.CertFile, "http-cert", "", "Path to client certificate")
+	fs.StringVar(&conf.KeyFile, "http-key", "", "Path to client certificate key")
+	fs.BoolVar(&conf.Insecure, "http-insecure", false, "Skip TLS certificate verification")
+	fs.StringVar(&conf.Username, "http-user", "", "Username for HTTP basic authentication")
+	fs.StringVar(&conf.Password, "http-password", "", "Password for HTTP basic authentication")
+	fs.StringVar(&conf.Token, "http-token", "", "Bearer token for HTTP authentication")
+	return conf
+}
+
+// SetHTTPConfig replaces the shared HTTP client
+func SetHTTPConfig(conf HTTPConfig) error {
+	client, err := NewHTTPClient(conf)
+	if err != nil {
+		return err
+	}
+	httpClient = client
+	return nil
+}
+
+// NewHTTPClient returns a client configured from conf
+func NewHTTPClient(conf HTTPConfig) (*HTTPClient, error) {
+	if conf.Timeout <= 0 {
+		conf.Timeout = DefaultHTTPTimeout
+	}
+	tlsConf, err := tlsConfig(conf)
+	if err != nil {
+		return nil, err
+	}
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   conf.Timeout,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		TLSClientConfig:     tlsConf,
+		TLSHandshakeTimeout: conf.Timeout,
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: 10,
+		IdleConnTimeout:     90 * time.Second,
+	}
+	return &HTTPClient{
+		client: &http.Client{
+			Timeout:   conf.Timeout,
+			Transport: transport,
+		},
+		conf: conf,
+	}, nil
+}
+
+func tlsConfig(conf HTTPConfig) (*tls.Config, error) {
+	tlsConf := &tls.Config{
+		InsecureSkipVerify: conf.Insecure,
+	}
+	if conf.CAFile != "" {
+		ca, err := ioutil.ReadFile(conf.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificate found in %s", conf.CAFile)
+		}
+		tlsConf.RootCAs = pool
+	}
+	if conf.CertFile != "" || conf.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(conf.CertFile, conf.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConf.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConf, nil
+}
+
+// URL builds a request URL for a service address with the configured scheme
+func (c *HTTPClient) URL(addr string, path string) string {
+	scheme := "http"
+	if c.conf.TLS {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s%s", scheme, addr, path)
+}
+
+// Get performs a GET request and returns the response body
+func (c *HTTPClient) Get(url string) (string, error) {
+	body, _, err := c.get(url)
+	return body, err
+}
+
+func (c *HTTPClient) get(url string) (string, int, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return "", 0, err
+	}
+	if c.conf.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.conf.Token)
+	} else if c.conf.Username != "" {
+		req.SetBasicAuth(c.conf.Username, c.conf.Password)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", 0, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", resp.StatusCode, err
+	}
+	return string(body), resp.StatusCode, nil
+}
+
+// URL builds a request URL using the shared HTTP client
+func URL(addr string, path string) string {
+	return httpClient.URL(addr, path)
+}
+
+/*
+HTTPGet - Wrapper for Get HTTP request
+*/
+func HTTPGet(url string) (string, error) {
+	return httpClient.Get(url)
+}
@@ -0,0 +1,105 @@
+// OpenIO netdata collectors
+// Copyright (C) 2019 OpenIO SAS
+//
+// This library is free software; you can redistribute it and/or
+// modify it under the terms of the GNU Lesser General Public
+// License as published by the Free Software Foundation; either
+// version 3.0 of the License, or (at your option) any later version.
+//
+// This library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package util
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHTTPClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(200 * time.Millisecond)
+		}
+		user, pass, _ := r.BasicAuth()
+		fmt.Fprintf(w, "%s|%s:%s", r.Header.Get("Authorization"), user, pass)
+	}))
+	defer srv.Close()
+	addr := strings.TrimPrefix(srv.URL, "http://")
+
+	tests := []struct {
+		name     string
+		conf     HTTPConfig
+		path     string
+		expected string
+		err      bool
+	}{
+		{
+			name:     "no auth",
+			path:     "/",
+			expected: "|:",
+		},
+		{
+			name:     "basic auth",
+			conf:     HTTPConfig{Username: "user", Password: "pass"},
+			path:     "/",
+			expected: "Basic dXNlcjpwYXNz|user:pass",
+		},
+		{
+			name:     "token",
+			conf:     HTTPConfig{Username: "user", Password: "pass", Token: "secret"},
+			path:     "/",
+			expected: "Bearer secret|:",
+		},
+		{
+			name: "timeout",
+			conf: HTTPConfig{Timeout: 50 * time.Millisecond},
+			path: "/slow",
+			err:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewHTTPClient(tt.conf)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			body, err := client.Get(client.URL(addr, tt.path))
+			if tt.err {
+				if err == nil {
+					t.Fatalf("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if body != tt.expected {
+				t.Fatalf("unexpected body got %q expected %q", body, tt.expected)
+			}
+		})
+	}
+}
+
+func TestHTTPClientTLS(t *testing.T) {
+	client, err := NewHTTPClient(HTTPConfig{TLS: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if url := client.URL("127.0.0.1:6006", "/stat"); url != "https://127.0.0.1:6006/stat" {
+		t.Fatalf("unexpected url %s", url)
+	}
+	if _, err := NewHTTPClient(HTTPConfig{CAFile: "/nonexistent"}); err == nil {
+		t.Fatalf("expected error on missing CA file")
+	}
+}
@@ -0,0 +1,124 @@
+// OpenIO netdata collectors
+// Copyright (C) 2019 OpenIO SAS
+//
+// This library is free software; you can redistribute it and/or
+// modify it under the terms of the GNU Lesser General Public
+// License as published by the Free Software Foundation; either
+// version 3.0 of the License, or (at your option) any later version.
+//
+// This library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package util
+
+import (
+	"math"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Sample -- counter value and the time it was read at
+type Sample struct {
+	Value uint64    `json:"value"`
+	Time  time.Time `json:"time"`
+}
+
+// Rates -- converts monotonic counters into per second rates
+type Rates struct {
+	sync.Mutex
+	samples map[string]Sample
+}
+
+// NewRates returns an empty rate tracker
+func NewRates() *Rates {
+	return &Rates{
+		samples: make(map[string]Sample),
+	}
+}
+
+// ParseCounter parses a counter value as an unsigned 64 bit integer,
+// accepting float notation for services that print counters as such
+func ParseCounter(value string) (uint64, error) {
+	v, err := strconv.ParseUint(value, 10, 64)
+	if err == nil {
+		return v, nil
+	}
+	f, ferr := strconv.ParseFloat(value, 64)
+	if ferr != nil || f < 0 || f >= math.MaxUint64 {
+		return 0, err
+	}
+	return uint64(f), nil
+}
+
+// Rate records the value of counter key read at time now, and returns the
+// per second rate since the previous sample. ok is false when there is no
+// previous sample, when no time elapsed or when the counter was reset
+func (r *Rates) Rate(key string, value uint64, now time.Time) (rate float64, ok bool) {
+	r.Lock()
+	prev, found := r.samples[key]
+	r.samples[key] = Sample{Value: value, Time: now}
+	r.Unlock()
+
+	if !found {
+		return 0, false
+	}
+	elapsed := now.Sub(prev.Time).Seconds()
+	if elapsed <= 0 {
+		return 0, false
+	}
+	delta, ok := counterDelta(prev.Value, value)
+	if !ok {
+		return 0, false
+	}
+	return float64(delta) / elapsed, true
+}
+
+// counterDelta returns the increase between two readings of a counter. A
+// decrease is a wraparound only if the previous value was within wrapMargin of
+// the limit of a 32 or 64 bit counter and the wrapped delta stays within the
+// same margin, and a reset otherwise, e.g. from 3e9 to 0 after a restart
+func counterDelta(prev, curr uint64) (uint64, bool) {
+	if curr >= prev {
+		return curr - prev, true
+	}
+	for _, max := range []uint64{math.MaxUint32, math.MaxUint64} {
+		margin := max / wrapMargin
+		if prev > max || max-prev > margin {
+			continue
+		}
+		if delta := max - prev + curr + 1; delta <= margin {
+			return delta, true
+		}
+	}
+	return 0, false
+}
+
+// wrapMargin -- fraction of the counter range, 1/1024, within which a decrease
+// near the limit is taken as a wraparound
+const wrapMargin = 1024
+
+// Snapshot returns a copy of the last samples, e.g. to persist them
+func (r *Rates) Snapshot() map[string]Sample {
+	r.Lock()
+	defer r.Unlock()
+	samples := make(map[string]Sample, len(r.samples))
+	for key, sample := range r.samples {
+		samples[key] = sample
+	}
+	return samples
+}
+
+// Restore loads samples saved by Snapshot
+func (r *Rates) Restore(samples map[string]Sample) {
+	r.Lock()
+	defer r.Unlock()
+	for key, sample := range samples {
+		r.samples[key] = sample
+	}
+}
@@ -0,0 +1,76 @@
+// OpenIO netdata collectors
+// Copyright (C) 2019 OpenIO SAS
+//
+// This library is free software; you can redistribute it and/or
+// modify it under the terms of the GNU Lesser General Public
+// License as published by the Free Software Foundation; either
+// version 3.0 of the License, or (at your option) any later version.
+//
+// This library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package util
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestRates(t *testing.T) {
+	start := time.Unix(1000, 0)
+	tests := []struct {
+		name    string
+		prev    uint64
+		curr    uint64
+		elapsed time.Duration
+		rate    float64
+		ok      bool
+	}{
+		{name: "increase", prev: 100, curr: 125, elapsed: 10 * time.Second, rate: 2.5, ok: true},
+		{name: "measured interval", prev: 100, curr: 130, elapsed: 12 * time.Second, rate: 2.5, ok: true},
+		{name: "no time elapsed", prev: 100, curr: 130, ok: false},
+		{name: "reset", prev: 5000, curr: 12, elapsed: 10 * time.Second, ok: false},
+		{name: "reset from 3e9", prev: 3e9, curr: 0, elapsed: 10 * time.Second, ok: false},
+		{name: "reset above 2^63", prev: 1<<63 + 5000, curr: 12, elapsed: 10 * time.Second, ok: false},
+		{name: "32 bit wrap", prev: math.MaxUint32 - 9, curr: 10, elapsed: 10 * time.Second, rate: 2, ok: true},
+		{name: "64 bit wrap", prev: math.MaxUint64 - 19, curr: 0, elapsed: 10 * time.Second, rate: 2, ok: true},
+		{name: "large values", prev: 1 << 40, curr: 1<<40 + 30, elapsed: 10 * time.Second, rate: 3, ok: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRates()
+			if _, ok := r.Rate("key", tt.prev, start); ok {
+				t.Fatalf("expected no rate on first sample")
+			}
+			rate, ok := r.Rate("key", tt.curr, start.Add(tt.elapsed))
+			if ok != tt.ok || rate != tt.rate {
+				t.Fatalf("unexpected rate got %v %v expected %v %v", rate, ok, tt.rate, tt.ok)
+			}
+		})
+	}
+}
+
+func TestParseCounter(t *testing.T) {
+	for value, expected := range map[string]uint64{
+		"42":                   42,
+		"18446744073709551615": math.MaxUint64,
+		"12.0":                 12,
+	} {
+		v, err := ParseCounter(value)
+		if err != nil || v != expected {
+			t.Fatalf("unexpected result for %s got %v %v", value, v, err)
+		}
+	}
+	for _, value := range []string{"-1", "abc", ""} {
+		if _, err := ParseCounter(value); err == nil {
+			t.Fatalf("expected error for %q", value)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+// OpenIO netdata collectors
+// Copyright (C) 2019 OpenIO SAS
+//
+// This library is free software; you can redistribute it and/or
+// modify it under the terms of the GNU Lesser General Public
+// License as published by the Free Software Foundation; either
+// version 3.0 of the License, or (at your option) any later version.
+//
+// This library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package util
+
+import (
+	"hash/fnv"
+	"math/rand"
+	"strconv"
+	"sync"
+)
+
+// Simulate -- collectors produce synthetic values instead of querying services
+var Simulate = false
+
+// Simulation -- source of evolving synthetic values, keyed by metric
+type Simulation struct {
+	sync.Mutex
+	rand   *rand.Rand
+	values map[string]float64
+}
+
+// NewSimulation returns a simulation seeded from name, so that instances
+// evolve differently but reproducibly
+func NewSimulation(name string) *Simulation {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	return &Simulation{
+		rand:   rand.New(rand.NewSource(int64(h.Sum64()))),
+		values: make(map[string]float64),
+	}
+}
+
+// GaugeValue returns a value drifting randomly between min and max
+func (s *Simulation) GaugeValue(key string, min, max float64) float64 {
+	s.Lock()
+	defer s.Unlock()
+	v, ok := s.values[key]
+	if !ok {
+		v = min + s.rand.Float64()*(max-min)
+	} else {
+		// Move by up to 5% of the range per call
+		v += (s.rand.Float64() - 0.5) * (max - min) / 10
+	}
+	if v < min {
+		v = min
+	} else if v > max {
+		v = max
+	}
+	s.values[key] = v
+	return v
+}
+
+// CounterValue returns a monotonic counter increasing by about rate per call
+func (s *Simulation) CounterValue(key string, rate float64) float64 {
+	s.Lock()
+	defer s.Unlock()
+	v, ok := s.values[key]
+	if !ok {
+		v = s.rand.Float64() * rate * 1000
+	}
+	v += rate * (0.5 + s.rand.Float64())
+	s.values[key] = v
+	return v
+}
+
+// Gauge formats GaugeValue as an integer
+func (s *Simulation) Gauge(key string, min, max float64) string {
+	return strconv.FormatInt(int64(s.GaugeValue(key, min, max)), 10)
+}
+
+// Counter formats CounterValue as an integer
+func (s *Simulation) Counter(key string, rate float64) string {
+	return strconv.FormatUint(uint64(s.CounterValue(key, rate)), 10)
+}
+
+// Ratio formats GaugeValue with two decimals
+func (s *Simulation) Ratio(key string, min, max float64) string {
+	return strconv.FormatFloat(s.GaugeValue(key, min, max), 'f', 2, 64)
+}
@@ -0,0 +1,41 @@
+// OpenIO netdata collectors
+// Copyright (C) 2019 OpenIO SAS
+//
+// This library is free software; you can redistribute it and/or
+// modify it under the terms of the GNU Lesser General Public
+// License as published by the Free Software Foundation; either
+// version 3.0 of the License, or (at your option) any later version.
+//
+// This library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package util
+
+import (
+	"testing"
+)
+
+func TestSimulation(t *testing.T) {
+	a, b := NewSimulation("redis1"), NewSimulation("redis1")
+	var last float64
+	for i := 0; i < 100; i++ {
+		gauge := a.GaugeValue("memory", 10, 20)
+		if gauge < 10 || gauge > 20 {
+			t.Fatalf("gauge out of bounds: %v", gauge)
+		}
+		if other := b.GaugeValue("memory", 10, 20); other != gauge {
+			t.Fatalf("simulations with the same name differ: %v != %v", gauge, other)
+		}
+		counter := a.CounterValue("commands", 100)
+		if counter <= last {
+			t.Fatalf("counter decreased: %v after %v", counter, last)
+		}
+		last = counter
+		b.CounterValue("commands", 100)
+	}
+}
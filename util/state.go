@@ -0,0 +1,100 @@
+// OpenIO netdata collectors
+// Copyright (C) 2019 OpenIO SAS
+//
+// This library is free software; you can redistribute it and/or
+// modify it under the terms of the GNU Lesser General Public
+// License as published by the Free Software Foundation; either
+// version 3.0 of the License, or (at your option) any later version.
+//
+// This library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package util
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DefaultCacheDir -- used when netdata does not export NETDATA_CACHE_DIR
+const DefaultCacheDir = "/var/cache/netdata"
+
+// StateStore -- persists a collector state as JSON in netdata's cache directory
+type StateStore struct {
+	path   string
+	maxAge time.Duration
+}
+
+type stateFile struct {
+	Timestamp int64           `json:"timestamp"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// CacheDir returns the netdata cache directory
+func CacheDir() string {
+	if dir := os.Getenv("NETDATA_CACHE_DIR"); dir != "" {
+		return dir
+	}
+	return DefaultCacheDir
+}
+
+// NewStateStore returns a store named after the plugin; states older than
+// maxAge are ignored on load
+func NewStateStore(name string, maxAge time.Duration) *StateStore {
+	return &StateStore{
+		path:   filepath.Join(CacheDir(), name+".state.json"),
+		maxAge: maxAge,
+	}
+}
+
+// Save writes data to the store
+func (s *StateStore) Save(data interface{}) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	out, err := json.Marshal(stateFile{Timestamp: time.Now().Unix(), Data: raw})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	// Write then rename so that a crash never leaves a truncated state
+	tmp := s.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, out, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// Load reads the stored state into data. It returns false when there is no
+// state or when it has expired
+func (s *StateStore) Load(data interface{}) (bool, error) {
+	in, err := ioutil.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	state := stateFile{}
+	if err := json.Unmarshal(in, &state); err != nil {
+		return false, err
+	}
+	if time.Since(time.Unix(state.Timestamp, 0)) > s.maxAge {
+		return false, nil
+	}
+	if err := json.Unmarshal(state.Data, data); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,57 @@
+// OpenIO netdata collectors
+// Copyright (C) 2019 OpenIO SAS
+//
+// This library is free software; you can redistribute it and/or
+// modify it under the terms of the GNU Lesser General Public
+// License as published by the Free Software Foundation; either
+// version 3.0 of the License, or (at your option) any later version.
+//
+// This library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package util
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestStateStore(t *testing.T) {
+	path, err := ioutil.TempDir("", "test_state_")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(path)
+	os.Setenv("NETDATA_CACHE_DIR", path)
+	defer os.Unsetenv("NETDATA_CACHE_DIR")
+
+	store := NewStateStore("test", time.Minute)
+	data := map[string]int{}
+	if ok, err := store.Load(&data); ok || err != nil {
+		t.Fatalf("expected no state, got %v %v", ok, err)
+	}
+
+	saved := map[string]int{"req.hits.OPENIO.rawx": 42}
+	if err := store.Save(saved); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ok, err := store.Load(&data); !ok || err != nil {
+		t.Fatalf("expected state, got %v %v", ok, err)
+	}
+	if !reflect.DeepEqual(data, saved) {
+		t.Fatalf("unexpected state got %v expected %v", data, saved)
+	}
+
+	expired := NewStateStore("test", -time.Second)
+	if ok, err := expired.Load(&data); ok || err != nil {
+		t.Fatalf("expected expired state, got %v %v", ok, err)
+	}
+}
@@ -0,0 +1,183 @@
+// OpenIO netdata collectors
+// Copyright (C) 2019 OpenIO SAS
+//
+// This library is free software; you can redistribute it and/or
+// modify it under the terms of the GNU Lesser General Public
+// License as published by the Free Software Foundation; either
+// version 3.0 of the License, or (at your option) any later version.
+//
+// This library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package util
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TargetUsage -- help text for flags taking a list of targets
+const TargetUsage = "Comma separated list of targets: IP:PORT, tcp://HOST:PORT, tls://HOST:PORT or unix:///PATH, " +
+	"with optional ?name=NAME"
+
+// Target -- address of a service reached over TCP, TLS or a unix socket
+type Target struct {
+	// Network is tcp or unix
+	Network string
+	// Addr is host:port, or the socket path for unix targets
+	Addr string
+	// Name identifies the target in charts, defaults to Addr
+	Name string
+	// Params holds the query parameters of the target URI
+	Params url.Values
+	// Extra holds the fields following IP:PORT in the legacy syntax
+	Extra []string
+
+	tls *tls.Config
+}
+
+// ParseTargets parses a comma separated list of targets
+func ParseTargets(targets string) ([]Target, error) {
+	var res []Target
+	for _, raw := range strings.Split(targets, ",") {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+		t, err := ParseTarget(raw)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, t)
+	}
+	if len(res) == 0 {
+		return nil, fmt.Errorf("no target found in %q", targets)
+	}
+	return res, nil
+}
+
+// ParseTarget parses a target, either as an URI such as
+// tcp://[::1]:6011?name=meta, tls://host:port or unix:///run/redis.sock,
+// or with the legacy IP:PORT[:EXTRA]... syntax
+func ParseTarget(raw string) (Target, error) {
+	if !strings.Contains(raw, "://") {
+		return parseLegacyTarget(raw)
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return Target{}, err
+	}
+	t := Target{Params: u.Query()}
+	switch u.Scheme {
+	case "tcp", "tls":
+		if _, _, err := net.SplitHostPort(u.Host); err != nil {
+			return Target{}, fmt.Errorf("invalid target %s: %v", raw, err)
+		}
+		t.Network = "tcp"
+		t.Addr = u.Host
+	case "unix":
+		if u.Path == "" {
+			return Target{}, fmt.Errorf("invalid target %s: missing socket path", raw)
+		}
+		t.Network = "unix"
+		t.Addr = u.Path
+	default:
+		return Target{}, fmt.Errorf("invalid target %s: unsupported scheme %s", raw, u.Scheme)
+	}
+	if u.Scheme == "tls" {
+		if t.tls, err = t.tlsConfig(); err != nil {
+			return Target{}, err
+		}
+	}
+	t.Name = t.Params.Get("name")
+	if t.Name == "" {
+		t.Name = t.Addr
+	}
+	return t, nil
+}
+
+func parseLegacyTarget(raw string) (Target, error) {
+	host, rest := raw, ""
+	if strings.HasPrefix(raw, "[") {
+		end := strings.Index(raw, "]")
+		if end < 0 {
+			return Target{}, fmt.Errorf("invalid target %s: missing ]", raw)
+		}
+		host, rest = raw[1:end], strings.TrimPrefix(raw[end+1:], ":")
+	} else if pos := strings.Index(raw, ":"); pos >= 0 {
+		host, rest = raw[:pos], raw[pos+1:]
+	}
+	fields := strings.Split(rest, ":")
+	if host == "" || fields[0] == "" {
+		return Target{}, fmt.Errorf("invalid target %s: must be IP:PORT", raw)
+	}
+	if _, err := strconv.ParseUint(fields[0], 10, 16); err != nil {
+		return Target{}, fmt.Errorf("invalid target %s: invalid port %s", raw, fields[0])
+	}
+	addr := net.JoinHostPort(host, fields[0])
+	return Target{
+		Network: "tcp",
+		Addr:    addr,
+		Name:    addr,
+		Params:  url.Values{},
+		Extra:   fields[1:],
+	}, nil
+}
+
+func (t Target) tlsConfig() (*tls.Config, error) {
+	insecure, _ := strconv.ParseBool(t.Params.Get("insecure"))
+	conf, err := tlsConfig(HTTPConfig{
+		CAFile:   t.Params.Get("ca"),
+		CertFile: t.Params.Get("cert"),
+		KeyFile:  t.Params.Get("key"),
+		Insecure: insecure,
+	})
+	if err != nil {
+		return nil, err
+	}
+	conf.ServerName = t.Params.Get("servername")
+	if conf.ServerName == "" {
+		conf.ServerName, _, _ = net.SplitHostPort(t.Addr)
+	}
+	return conf, nil
+}
+
+// TLS tells whether the connection to the target is encrypted
+func (t Target) TLS() bool {
+	return t.tls != nil
+}
+
+// String returns the target URI
+func (t Target) String() string {
+	scheme := t.Network
+	if t.TLS() {
+		scheme = "tls"
+	}
+	return scheme + "://" + t.Addr
+}
+
+// Dial connects to the target like Dial, performing the TLS handshake for
+// tls targets
+func (t Target) Dial(timeout time.Duration) (net.Conn, time.Duration, error) {
+	start := time.Now()
+	conn, _, err := Dial(t.Network, t.Addr, timeout)
+	if err != nil || t.tls == nil {
+		return conn, time.Since(start), err
+	}
+	tlsConn := tls.Client(conn, t.tls)
+	if err := tlsConn.Handshake(); err != nil {
+		conn.Close()
+		return nil, 0, err
+	}
+	return tlsConn, time.Since(start), nil
+}
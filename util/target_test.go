@@ -0,0 +1,92 @@
+// OpenIO netdata collectors
+// Copyright (C) 2019 OpenIO SAS
+//
+// This library is free software; you can redistribute it and/or
+// modify it under the terms of the GNU Lesser General Public
+// License as published by the Free Software Foundation; either
+// version 3.0 of the License, or (at your option) any later version.
+//
+// This library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package util
+
+import (
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestParseTarget(t *testing.T) {
+	tests := []struct {
+		raw     string
+		network string
+		addr    string
+		name    string
+		tls     bool
+		extra   []string
+		cluster string
+		err     bool
+	}{
+		{raw: "10.0.0.1:6011:redis", network: "tcp", addr: "10.0.0.1:6011", name: "10.0.0.1:6011", extra: []string{"redis"}},
+		{raw: "10.0.0.1:6014:tube1:tube2", network: "tcp", addr: "10.0.0.1:6014", name: "10.0.0.1:6014", extra: []string{"tube1", "tube2"}},
+		{raw: "[::1]:6011", network: "tcp", addr: "[::1]:6011", name: "[::1]:6011", extra: []string{}},
+		{raw: "tcp://[::1]:6011?name=meta&cluster=c1", network: "tcp", addr: "[::1]:6011", name: "meta", cluster: "c1"},
+		{raw: "unix:///run/redis.sock", network: "unix", addr: "/run/redis.sock", name: "/run/redis.sock"},
+		{raw: "tls://redis.local:6380", network: "tcp", addr: "redis.local:6380", name: "redis.local:6380", tls: true},
+		{raw: "10.0.0.1", err: true},
+		{raw: "10.0.0.1:port", err: true},
+		{raw: "tcp://10.0.0.1", err: true},
+		{raw: "udp://10.0.0.1:53", err: true},
+		{raw: "unix://", err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			target, err := ParseTarget(tt.raw)
+			if tt.err {
+				if err == nil {
+					t.Fatalf("expected error, got %+v", target)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if target.Network != tt.network || target.Addr != tt.addr || target.Name != tt.name || target.TLS() != tt.tls {
+				t.Fatalf("unexpected target %+v", target)
+			}
+			if tt.extra != nil && !reflect.DeepEqual(target.Extra, tt.extra) {
+				t.Fatalf("unexpected extra fields %v", target.Extra)
+			}
+			if cluster := target.Params.Get("cluster"); cluster != tt.cluster {
+				t.Fatalf("unexpected cluster %s", cluster)
+			}
+		})
+	}
+}
+
+func TestTargetDial(t *testing.T) {
+	l, err := net.Listen("tcp", "[::1]:0")
+	if err != nil {
+		t.Skipf("no IPv6 loopback: %v", err)
+	}
+	defer l.Close()
+
+	targets, err := ParseTargets("tcp://" + l.Addr().String() + "?name=test, " + l.Addr().String())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, target := range targets {
+		conn, _, err := target.Dial(DefaultDialTimeout)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		conn.Close()
+	}
+}
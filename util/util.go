@@ -21,10 +21,10 @@ import (
 	"fmt"
 	"gopkg.in/yaml.v2"
 	"io/ioutil"
-	"log"
 	"net"
-	"net/http"
+	"oionetdata/logger"
 	"os"
+	"regexp"
 	"strconv"
 	"strings"
 	"syscall"
@@ -41,6 +41,8 @@ var mReplacer = strings.NewReplacer(
 	"/", "_",
 )
 
+var ipv6Literal = regexp.MustCompile(`\[[0-9A-Fa-f:.]+\]`)
+
 type Command struct {
 	Name         string `yaml:"name"`
 	Command      string `yaml:"command"`
@@ -78,38 +80,36 @@ func VolumeInfo(volume string) (map[string]uint64, string, error) {
 	return vMetric, fsId, nil
 }
 
-/*
-HTTPGet - Wrapper for Get HTTP request
-*/
-func HTTPGet(url string) (string, error) {
-	resp, err := http.Get(url)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return "", err
-	}
-	return string(body), nil
-}
-
 func getIPList() (map[string]bool, error) {
 	ipList := make(map[string]bool)
 	ifaces, err := net.InterfaceAddrs()
 	if err != nil {
 		return nil, err
 	}
-	for ip := range ifaces {
-		// TODO: consider adding support for IPv6 here
-		ips := strings.Split(ifaces[ip].String(), "/")[0]
-		if net.ParseIP(ips).To4() != nil {
-			ipList[ips] = true
+	for _, addr := range ifaces {
+		ip, _, err := net.ParseCIDR(addr.String())
+		if err != nil {
+			continue
 		}
+		ipList[ip.String()] = true
 	}
 	return ipList, nil
 }
 
+// SplitHost returns the host part of a service address, which may be
+// host:port, [ipv6]:port or a bare host
+func SplitHost(service string) string {
+	host, _, err := net.SplitHostPort(service)
+	if err != nil {
+		host = strings.TrimSuffix(strings.TrimPrefix(service, "["), "]")
+	}
+	// Drop the zone of link-local addresses (fe80::1%eth0)
+	if pos := strings.Index(host, "%"); pos >= 0 {
+		host = host[:pos]
+	}
+	return host
+}
+
 // IsSameHost -- checks if a service if on the current host
 func IsSameHost(service string) bool {
 	if ForceRemote {
@@ -119,21 +119,37 @@ func IsSameHost(service string) bool {
 		var err error
 		ipList, err = getIPList()
 		if err != nil {
-			log.Println("WARN could not determine if same host, assuming not", err)
+			logger.Warn("Could not determine if same host, assuming not", "err", err)
 			return false
 		}
 	}
-	serviceIP := strings.Split(service, ":")[0]
-	_, ok := ipList[serviceIP]
+	ip := net.ParseIP(SplitHost(service))
+	if ip == nil {
+		return false
+	}
+	_, ok := ipList[ip.String()]
 	return ok
 }
 
+// AddrID returns a netdata-safe identifier for a service address. IPv6
+// literals are written in canonical form so that the ID does not depend on
+// how the address was spelled
+func AddrID(service string) string {
+	service = ipv6Literal.ReplaceAllStringFunc(service, func(literal string) string {
+		if ip := net.ParseIP(strings.Trim(literal, "[]")); ip != nil {
+			return ip.String()
+		}
+		return literal
+	})
+	return mReplacer.Replace(service)
+}
+
 // SID -- Get a service ID for netdata
 func SID(service string, ns string, volume ...string) string {
 	if (len(volume) > 0) && (volume[0] != "") {
-		return fmt.Sprintf("%s.%s.%s", ns, mReplacer.Replace(service), volume[0])
+		return fmt.Sprintf("%s.%s.%s", ns, AddrID(service), volume[0])
 	}
-	return fmt.Sprintf("%s.%s", ns, mReplacer.Replace(service))
+	return fmt.Sprintf("%s.%s", ns, AddrID(service))
 }
 
 // AcctID -- get an ID from a ns/account/container
@@ -18,6 +18,7 @@ package util
 
 import (
 	"io/ioutil"
+	"net"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -111,3 +112,39 @@ bind 10.240.0.13
 	}
 
 }
+
+func TestSID(t *testing.T) {
+	tests := map[string]string{
+		"10.240.0.13:6200":           "OPENIO.10_240_0_13_6200",
+		"rawx_10.240.0.13:6200":      "OPENIO.rawx_10_240_0_13_6200",
+		"[2001:db8::1]:6200":         "OPENIO.2001_db8__1_6200",
+		"[2001:0db8:0:0::0001]:6200": "OPENIO.2001_db8__1_6200",
+		"rawx_[fd00::a:1]:6200":      "OPENIO.rawx_fd00__a_1_6200",
+	}
+	for service, expected := range tests {
+		if sid := SID(service, "OPENIO"); sid != expected {
+			t.Fatalf("unexpected SID for %s got %s expected %s", service, sid, expected)
+		}
+	}
+}
+
+func TestIsSameHost(t *testing.T) {
+	tests := map[string]bool{
+		"127.0.0.1:6000":     true,
+		"127.0.0.1":          true,
+		"192.0.2.1:6000":     false,
+		"[2001:db8::1]:6000": false,
+		"not an address":     false,
+	}
+	// Only check IPv6 loopback when the host has one
+	if l, err := net.Listen("tcp", "[::1]:0"); err == nil {
+		l.Close()
+		tests["[::1]:6000"] = true
+		tests["[0:0::1]:6000"] = true
+	}
+	for service, expected := range tests {
+		if local := IsSameHost(service); local != expected {
+			t.Fatalf("unexpected result for %s got %v expected %v", service, local, expected)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+// OpenIO netdata collectors
+// Copyright (C) 2019 OpenIO SAS
+//
+// This library is free software; you can redistribute it and/or
+// modify it under the terms of the GNU Lesser General Public
+// License as published by the Free Software Foundation; either
+// version 3.0 of the License, or (at your option) any later version.
+//
+// This library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public
+// License along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package zookeeper
+
+import (
+	"oionetdata/util"
+	"time"
+)
+
+type simulator struct {
+	sim *util.Simulation
+}
+
+// NewSimulatedCollector returns a collector producing synthetic mntr fields
+// for target, without connecting to it
+func NewSimulatedCollector(target util.Target) *simulator {
+	return &simulator{
+		sim: util.NewSimulation("zookeeper/" + target.String()),
+	}
+}
+
+func (s *simulator) ConnectTime() time.Duration {
+	return time.Duration(s.sim.GaugeValue("connect_time", 100, 2000)) * time.Microsecond
+}
+
+func (s *simulator) Collect() (map[string]string, error) {
+	return map[string]string{
+		"zk_version":                    "3.4.9-simulated",
+		"zk_server_state":               "leader",
+		"zk_min_latency":                "0",
+		"zk_max_latency":                s.sim.Gauge("zk_max_latency", 50, 500),
+		"zk_avg_latency":                s.sim.Gauge("zk_avg_latency", 0, 5),
+		"zk_packets_received":           s.sim.Counter("zk_packets_received", 500),
+		"zk_packets_sent":               s.sim.Counter("zk_packets_sent", 500),
+		"zk_num_alive_connections":      s.sim.Gauge("zk_num_alive_connections", 10, 60),
+		"zk_outstanding_requests":       s.sim.Gauge("zk_outstanding_requests", 0, 5),
+		"zk_znode_count":                s.sim.Gauge("zk_znode_count", 1e4, 1.2e4),
+		"zk_watch_count":                s.sim.Gauge("zk_watch_count", 100, 300),
+		"zk_ephemerals_count":           s.sim.Gauge("zk_ephemerals_count", 50, 100),
+		"zk_approximate_data_size":      s.sim.Gauge("zk_approximate_data_size", 1e6, 2e6),
+		"zk_open_file_descriptor_count": s.sim.Gauge("zk_open_file_descriptor_count", 50, 100),
+		"zk_max_file_descriptor_count":  "4096",
+		"zk_pending_syncs":              "0",
+	}, nil
+}
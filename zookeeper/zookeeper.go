@@ -18,25 +18,38 @@ package zookeeper
 
 import (
 	"bufio"
-	"net"
+	"oionetdata/netdata"
+	"oionetdata/util"
 	"strings"
+	"time"
 )
 
 type collector struct {
-	addr string
+	target      util.Target
+	connectTime time.Duration
 }
 
 func NewCollector(addr string) *collector {
+	return NewTargetCollector(util.Target{Network: "tcp", Addr: addr, Name: addr})
+}
+
+func NewTargetCollector(target util.Target) *collector {
 	return &collector{
-		addr: addr,
+		target: target,
 	}
 }
 
+func (c *collector) ConnectTime() time.Duration {
+	return c.connectTime
+}
+
 func (c *collector) Collect() (map[string]string, error) {
-	conn, err := net.Dial("tcp", c.addr)
+	conn, connectTime, err := c.target.Dial(util.DefaultDialTimeout)
 	if err != nil {
 		return nil, err
 	}
+	defer conn.Close()
+	c.connectTime = connectTime
 
 	_, err = conn.Write([]byte("mntr\n"))
 	if err != nil {
@@ -53,10 +66,13 @@ func (c *collector) Collect() (map[string]string, error) {
 		}
 		data[kv[0]] = kv[1]
 	}
-	conn.Close()
 
 	if err := scanner.Err(); err != nil {
 		return nil, err
 	}
+	if len(data) == 0 {
+		// e.g. "This ZooKeeper instance is not currently serving requests"
+		return nil, netdata.ProtocolErrorf("no mntr fields in response from %s", c.target)
+	}
 	return data, nil
 }